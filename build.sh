@@ -5,7 +5,7 @@
 
 # Compile for Windows
 echo "Building Windows Binary..."
-GOOS=windows GOARCH=amd64 go build -o ./dist/jellyfindownloader.exe main.go
+GOOS=windows GOARCH=amd64 go build -o ./dist/jellyfindownloader.exe
 
 # Compile for Linux
 echo "Building Linux Binary..."
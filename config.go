@@ -0,0 +1,206 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"jf_requests/jf_requests"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/BurntSushi/toml"
+	"github.com/fatih/color"
+)
+
+// Returns the path of the default config file inside the users config directory.
+func GetDefaultConfigPath() string {
+	configDir, err := os.UserConfigDir()
+	if err != nil {
+		return ""
+	}
+
+	return filepath.Join(configDir, jf_requests.ConfigDirName, "config.toml")
+}
+
+// Converts a value of the config file into the string representation used by the flag package.
+func configValueToString(value any) string {
+	if list, ok := value.([]any); ok {
+		var values []string
+		for _, entry := range list {
+			values = append(values, fmt.Sprint(entry))
+		}
+
+		return strings.Join(values, ",")
+	}
+
+	return fmt.Sprint(value)
+}
+
+// Applies the values of the given config to all flags which were not passed on the command line.
+func applyConfigValues(values map[string]any, path string) error {
+	passedFlags := make(map[string]bool)
+	flag.Visit(func(f *flag.Flag) {
+		passedFlags[f.Name] = true
+	})
+
+	for key, value := range values {
+		if key == "config" || key == "profile" || flag.Lookup(key) == nil {
+			return errors.New(fmt.Sprintf("Unknown option '%s' in config file %s", key, path))
+		}
+
+		if passedFlags[key] {
+			continue
+		}
+
+		// Every entry of a list is passed separately to flags which can be repeated
+		list, isList := value.([]any)
+		if _, repeatable := flag.Lookup(key).Value.(*ListFlag); !isList || !repeatable {
+			list = []any{value}
+		}
+
+		for _, entry := range list {
+			if err := flag.Set(key, configValueToString(entry)); err != nil {
+				return errors.New(fmt.Sprintf("Invalid value for '%s' in config file %s: %s", key, path, err))
+			}
+		}
+	}
+
+	return nil
+}
+
+// Reads the TOML config file and returns its values and the profiles it defines. The path is empty
+// if there is no config file: a missing default config file is ignored, while a missing explicitly
+// given config file is an error.
+func readConfigFile(args *Arguments) (map[string]any, map[string]map[string]any, string, error) {
+	path := args.ConfigPath
+	if path == "" {
+		path = GetDefaultConfigPath()
+		if _, err := os.Stat(path); path == "" || errors.Is(err, os.ErrNotExist) {
+			return nil, nil, "", nil
+		}
+	}
+
+	var values map[string]any
+	if _, err := toml.DecodeFile(path, &values); err != nil {
+		return nil, nil, "", errors.New(fmt.Sprintf("Failed to read config file %s: %s", path, err))
+	}
+
+	profiles := make(map[string]map[string]any)
+	if rawProfiles, ok := values["profiles"]; ok {
+		table, ok := rawProfiles.(map[string]any)
+		if !ok {
+			return nil, nil, "", errors.New(fmt.Sprintf("Invalid profiles in config file %s, use a table per profile like [profiles.home]", path))
+		}
+
+		for name, rawProfile := range table {
+			profile, ok := rawProfile.(map[string]any)
+			if !ok {
+				return nil, nil, "", errors.New(fmt.Sprintf("Invalid profile '%s' in config file %s, use a table like [profiles.%s]", name, path, name))
+			}
+
+			profiles[name] = profile
+		}
+
+		delete(values, "profiles")
+	}
+
+	return values, profiles, path, nil
+}
+
+// Returns the name of the profile which is used: the one passed with -profile or the default
+// profile of the config file.
+func getProfileName(args *Arguments, values map[string]any) string {
+	if args.Profile != "" {
+		return args.Profile
+	}
+
+	name, _ := values["profile"].(string)
+	return name
+}
+
+// Returns the names of the profiles in alphabetical order.
+func getProfileNames(profiles map[string]map[string]any) []string {
+	names := make([]string, 0, len(profiles))
+	for name := range profiles {
+		names = append(names, name)
+	}
+
+	sort.Strings(names)
+	return names
+}
+
+// Loads the TOML config file and applies its values to all arguments which were not passed on the
+// command line. The keys of the config file are the names of the command line flags, e.g.
+//
+//	url = "https://jellyfin.example.com"
+//	username = "me"
+//	output = "/mnt/media"
+//
+// Values for single servers can be grouped into profiles like [profiles.home], which are selected
+// with -profile or the top level key profile. The values of the active profile take precedence over
+// the other values of the config file. Each profile has its own cached authentication token.
+func LoadConfigFile(args *Arguments) error {
+	values, profiles, path, err := readConfigFile(args)
+	if err != nil {
+		return err
+	} else if path == "" {
+		if args.Profile != "" {
+			return errors.New(fmt.Sprintf("Profile '%s' not found, there is no config file", args.Profile))
+		}
+
+		return nil
+	}
+
+	if name := getProfileName(args, values); name != "" {
+		profile, ok := profiles[name]
+		if !ok {
+			return errors.New(fmt.Sprintf("Profile '%s' not found in config file %s. Available profiles: %s", name, path, strings.Join(getProfileNames(profiles), ", ")))
+		}
+
+		// Flags set by the profile count as set, so the other values of the config file do not override them
+		if err := applyConfigValues(profile, fmt.Sprintf("%s (profile %s)", path, name)); err != nil {
+			return err
+		}
+
+		args.Profile = name
+		jf_requests.UseTokenCacheProfile(name)
+	}
+
+	delete(values, "profile")
+	return applyConfigValues(values, path)
+}
+
+// Lists the profiles of the config file with their server, user and output directory. Returns
+// false if the config file could not be read.
+func ListProfiles(args *Arguments) bool {
+	values, profiles, path, err := readConfigFile(args)
+	if err != nil {
+		color.Red(err.Error())
+		return false
+	} else if len(profiles) == 0 {
+		color.Yellow("No profiles defined in the config file. Add them as tables like [profiles.home].")
+		return true
+	}
+
+	defaultProfile, _ := values["profile"].(string)
+	fmt.Printf("Profiles in %s:\n", path)
+	for _, name := range getProfileNames(profiles) {
+		label := name
+		if name == defaultProfile {
+			label += " (default)"
+		}
+
+		details := []string{}
+		for _, key := range []string{"url", "username", "output"} {
+			if value, ok := profiles[name][key]; ok {
+				details = append(details, fmt.Sprintf("%s=%s", key, configValueToString(value)))
+			}
+		}
+
+		color.Cyan("  %s: %s", label, strings.Join(details, " "))
+	}
+
+	return true
+}
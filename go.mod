@@ -3,14 +3,20 @@ module jf_requests
 go 1.23
 
 require (
-	github.com/fatih/color v1.15.0 // indirect
-	github.com/lmittmann/tint v1.0.5 // indirect
+	github.com/BurntSushi/toml v1.6.0
+	github.com/fatih/color v1.15.0
+	github.com/lmittmann/tint v1.0.5
+	github.com/schollz/progressbar/v3 v3.13.1
+	golang.org/x/sys v0.10.0
+	golang.org/x/term v0.10.0
+	golang.org/x/text v0.14.0
+	golang.org/x/time v0.10.0
+)
+
+require (
 	github.com/mattn/go-colorable v0.1.13 // indirect
 	github.com/mattn/go-isatty v0.0.17 // indirect
 	github.com/mattn/go-runewidth v0.0.15 // indirect
 	github.com/mitchellh/colorstring v0.0.0-20190213212951-d06e56a500db // indirect
 	github.com/rivo/uniseg v0.4.4 // indirect
-	github.com/schollz/progressbar/v3 v3.13.1 // indirect
-	golang.org/x/sys v0.10.0 // indirect
-	golang.org/x/term v0.10.0 // indirect
 )
@@ -0,0 +1,74 @@
+package jf_requests
+
+import (
+	"fmt"
+	"net/url"
+	"path/filepath"
+	"strings"
+)
+
+// An image type of Jellyfin and the filename it is stored under, following the Kodi conventions.
+type artworkType struct {
+	ImageType string
+	Filename  string
+	Format    string
+}
+
+var itemArtwork = []artworkType{
+	{ImageType: "Primary", Filename: "poster.jpg", Format: "Jpg"},
+	{ImageType: "Backdrop", Filename: "fanart.jpg", Format: "Jpg"},
+	{ImageType: "Logo", Filename: "logo.png", Format: "Png"},
+}
+
+func GetImageLinkForId(baseUrl string, token string, id string, imageType string, format string) string {
+	return BuildUrl(baseUrl, fmt.Sprintf("Items/%s/Images/%s", id, imageType), url.Values{"format": {format}, "api_key": {token}})
+}
+
+// Returns the download jobs for the artwork of the given item. Every file is stored in dir, its
+// filename is prefixed with prefix. Missing images are skipped.
+func GetArtworkJobs(baseUrl string, token string, id string, name string, dir string, prefix string) []DownloadJob {
+	var jobs []DownloadJob
+	for _, artwork := range itemArtwork {
+		jobs = append(jobs, DownloadJob{
+			Name:     fmt.Sprintf("%s (%s)", name, strings.ToLower(artwork.ImageType)),
+			Url:      GetImageLinkForId(baseUrl, token, id, artwork.ImageType, artwork.Format),
+			Outfile:  filepath.Join(dir, prefix+artwork.Filename),
+			Optional: true,
+		})
+	}
+
+	return jobs
+}
+
+// Returns the download jobs for the artwork of the series and the posters of the given seasons.
+func GetSeriesArtworkJobs(baseUrl string, token string, series *Series, seasons []Season, opts DownloadOptions) []DownloadJob {
+	dir := GetOutputPath(opts.OutputDir, opts.Layout.GetSeriesDir(series))
+	jobs := GetArtworkJobs(baseUrl, token, series.Id, series.Name, dir, "")
+
+	for _, season := range seasons {
+		filename := fmt.Sprintf("season%02d-poster.jpg", season.Index)
+		if season.Index == 0 {
+			filename = "season-specials-poster.jpg"
+		}
+
+		jobs = append(jobs, DownloadJob{
+			Name:     fmt.Sprintf("%s %s (poster)", series.Name, season.Name),
+			Url:      GetImageLinkForId(baseUrl, token, season.Id, "Primary", "Jpg"),
+			Outfile:  filepath.Join(dir, filename),
+			Optional: true,
+		})
+	}
+
+	return jobs
+}
+
+// Returns the download jobs for the artwork of the given movie. In the flat layout the artwork is
+// named after the movie file, otherwise it is stored in the movie directory.
+func GetMovieArtworkJobs(baseUrl string, token string, movie *Movie, opts DownloadOptions) []DownloadJob {
+	moviePath := movie.getPreservedOutfile(GetOutputPath(opts.OutputDir, opts.Layout.GetMoviePath(movie)), opts)
+	if opts.Layout == LayoutFlat || opts.Layout == "" {
+		return GetArtworkJobs(baseUrl, token, movie.Id, movie.Name, filepath.Dir(moviePath), filepath.Base(moviePath)+"-")
+	}
+
+	return GetArtworkJobs(baseUrl, token, movie.Id, movie.Name, filepath.Dir(moviePath), "")
+}
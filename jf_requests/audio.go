@@ -0,0 +1,59 @@
+package jf_requests
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Returns a short description of the given audio stream, e.g. "1: eng (AAC - 5.1)".
+func FormatAudioStream(stream MediaStream) string {
+	language := stream.Language
+	if language == "" {
+		language = "und"
+	}
+
+	if stream.Title == "" {
+		return fmt.Sprintf("%d: %s", stream.Index, language)
+	}
+
+	return fmt.Sprintf("%d: %s (%s)", stream.Index, language, stream.Title)
+}
+
+// Returns a comma separated description of all given audio streams.
+func FormatAudioStreams(streams []MediaStream) string {
+	var formatted []string
+	for _, stream := range streams {
+		formatted = append(formatted, FormatAudioStream(stream))
+	}
+
+	return strings.Join(formatted, ", ")
+}
+
+// Selects the audio stream of the given source. wanted is either the index of the stream or a two
+// or three letter language code. If multiple streams match the language, the default stream is preferred.
+func SelectAudioStream(source *MediaSource, wanted string) (*MediaStream, error) {
+	streams := source.GetStreams("Audio")
+
+	var selected *MediaStream
+	if index, err := strconv.Atoi(wanted); err == nil {
+		for idx := range streams {
+			if streams[idx].Index == index {
+				selected = &streams[idx]
+			}
+		}
+	} else {
+		selected = findLanguageStream(streams, wanted)
+	}
+
+	if selected == nil {
+		if len(streams) == 0 {
+			return nil, errors.New(fmt.Sprintf("Audio track '%s' not found, the item has no audio tracks", wanted))
+		}
+
+		return nil, errors.New(fmt.Sprintf("Audio track '%s' not found. Available audio tracks: %s", wanted, FormatAudioStreams(streams)))
+	}
+
+	return selected, nil
+}
@@ -0,0 +1,44 @@
+package jf_requests
+
+import "context"
+
+// Files of multiple items which are downloaded together by a single call of DownloadJobs, so
+// they share one pool of opts.Concurrency workers and one progress view. The files of every item
+// are added once they are resolved and confirmed, and are handed back to the item after the run.
+type DownloadBatch struct {
+	jobs    []DownloadJob
+	entries []batchEntry
+}
+
+// The files of an item within the batch.
+type batchEntry struct {
+	count  int
+	finish func(results []DownloadResult)
+}
+
+// Adds the jobs of an item to the batch. finish is called with the results of these jobs, in the
+// same order, once the batch ran.
+func (batch *DownloadBatch) Add(jobs []DownloadJob, finish func(results []DownloadResult)) {
+	batch.jobs = append(batch.jobs, jobs...)
+	batch.entries = append(batch.entries, batchEntry{count: len(jobs), finish: finish})
+}
+
+// Returns the number of files which were added to the batch.
+func (batch *DownloadBatch) Size() int {
+	return len(batch.jobs)
+}
+
+// Downloads the jobs of all items and passes the results on to the items in the order they were
+// added. The batch is empty afterwards.
+func (batch *DownloadBatch) Run(ctx context.Context, opts DownloadOptions) {
+	results := DownloadJobs(ctx, batch.jobs, opts)
+
+	offset := 0
+	for _, entry := range batch.entries {
+		entry.finish(results[offset : offset+entry.count])
+		offset += entry.count
+	}
+
+	batch.jobs = nil
+	batch.entries = nil
+}
@@ -0,0 +1,149 @@
+package jf_requests
+
+import (
+	"fmt"
+	"net/url"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Jellyfin counts time in ticks of 100 nanoseconds.
+const tickDuration = 100 * time.Nanosecond
+
+// A chapter marker of a media item.
+type Chapter struct {
+	Name  string
+	Start time.Duration
+}
+
+// Resolution of the trickplay thumbnails of a media item. The thumbnails are combined into tiles of
+// TileWidth x TileHeight thumbnails.
+type TrickplayInfo struct {
+	Width          int
+	Height         int
+	TileWidth      int
+	TileHeight     int
+	ThumbnailCount int
+}
+
+// Parses the chapters of the given raw item.
+func GetChaptersFromRawItem(rawItem map[string]any) []Chapter {
+	rawChapters, _ := rawItem["Chapters"].([]any)
+
+	var chapters []Chapter
+	for _, rawChapter := range rawChapters {
+		chapter := rawChapter.(map[string]any)
+		ticks, _ := chapter["StartPositionTicks"].(float64)
+		chapters = append(chapters, Chapter{
+			Name:  GetStringFromRawItem(chapter, "Name"),
+			Start: time.Duration(ticks) * tickDuration,
+		})
+	}
+
+	return chapters
+}
+
+// Returns the length of the given raw item or 0 if it is unknown.
+func GetRunTimeFromRawItem(rawItem map[string]any) time.Duration {
+	ticks, _ := rawItem["RunTimeTicks"].(float64)
+	return time.Duration(ticks) * tickDuration
+}
+
+// Parses the trickplay information of the given media source from the raw item. If multiple
+// resolutions exist, the largest one is returned. Returns nil if there are no trickplay thumbnails.
+func GetTrickplayFromRawItem(rawItem map[string]any, mediaSourceId string) *TrickplayInfo {
+	sources, _ := rawItem["Trickplay"].(map[string]any)
+	resolutions, _ := sources[mediaSourceId].(map[string]any)
+
+	var best *TrickplayInfo
+	for _, rawResolution := range resolutions {
+		resolution := rawResolution.(map[string]any)
+		info := TrickplayInfo{
+			Width:          GetIntFromRawItem(resolution, "Width", 0),
+			Height:         GetIntFromRawItem(resolution, "Height", 0),
+			TileWidth:      GetIntFromRawItem(resolution, "TileWidth", 0),
+			TileHeight:     GetIntFromRawItem(resolution, "TileHeight", 0),
+			ThumbnailCount: GetIntFromRawItem(resolution, "ThumbnailCount", 0),
+		}
+
+		if info.TileWidth > 0 && info.TileHeight > 0 && (best == nil || info.Width > best.Width) {
+			best = &info
+		}
+	}
+
+	return best
+}
+
+// Escapes the special characters of the ffmetadata format.
+func escapeFfmetadata(value string) string {
+	replacer := strings.NewReplacer(`\`, `\\`, "=", `\=`, ";", `\;`, "#", `\#`, "\n", `\`+"\n")
+	return replacer.Replace(value)
+}
+
+// Returns the job which writes the chapters of the item into an ffmetadata file next to videoOutfile.
+// The file can be merged into the video with ffmpeg -i video -i chapters.ffmetadata -map_metadata 1 -codec copy.
+// Returns nil if the item has no chapters.
+func (item *MediaItem) GetChapterJob(videoOutfile string) *DownloadJob {
+	if len(item.Chapters) == 0 {
+		return nil
+	}
+
+	var content strings.Builder
+	content.WriteString(";FFMETADATA1\n")
+
+	for idx, chapter := range item.Chapters {
+		end := item.RunTime
+		if idx+1 < len(item.Chapters) {
+			end = item.Chapters[idx+1].Start
+		}
+
+		// The last chapter ends with the item, if its length is unknown it gets a length of zero
+		end = max(end, chapter.Start)
+
+		name := chapter.Name
+		if name == "" {
+			name = fmt.Sprintf("Chapter %d", idx+1)
+		}
+
+		fmt.Fprintf(&content, "\n[CHAPTER]\nTIMEBASE=1/1000\nSTART=%d\nEND=%d\ntitle=%s\n",
+			chapter.Start.Milliseconds(), end.Milliseconds(), escapeFfmetadata(name))
+	}
+
+	basename := strings.TrimSuffix(videoOutfile, filepath.Ext(videoOutfile))
+	return &DownloadJob{
+		Name:    fmt.Sprintf("%s (chapters)", item.Name),
+		Outfile: basename + ".ffmetadata",
+		Content: []byte(content.String()),
+	}
+}
+
+// Returns the download jobs for the trickplay tiles of the item. The tiles are stored in the
+// same layout Jellyfin uses for local trickplay files, e.g. "Movie.trickplay/320 - 10x10/0.jpg".
+func (item *MediaItem) GetTrickplayJobs(baseUrl string, token string, videoOutfile string) []DownloadJob {
+	source := item.GetPrimarySource()
+	if item.Trickplay == nil || source == nil {
+		return nil
+	}
+
+	info := item.Trickplay
+	thumbnailsPerTile := info.TileWidth * info.TileHeight
+	tiles := (info.ThumbnailCount + thumbnailsPerTile - 1) / thumbnailsPerTile
+
+	basename := strings.TrimSuffix(videoOutfile, filepath.Ext(videoOutfile))
+	dir := filepath.Join(basename+".trickplay", fmt.Sprintf("%d - %dx%d", info.Width, info.TileWidth, info.TileHeight))
+
+	var jobs []DownloadJob
+	for tile := 0; tile < tiles; tile++ {
+		jobs = append(jobs, DownloadJob{
+			Name: fmt.Sprintf("%s (trickplay %d/%d)", item.Name, tile+1, tiles),
+			Url: BuildUrl(baseUrl, fmt.Sprintf("Videos/%s/Trickplay/%d/%d.jpg", item.Id, info.Width, tile),
+				url.Values{"mediaSourceId": {source.Id}, "api_key": {token}}),
+			Outfile:  filepath.Join(dir, strconv.Itoa(tile)+".jpg"),
+			Optional: true,
+		})
+	}
+
+	return jobs
+}
@@ -0,0 +1,96 @@
+package jf_requests
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+)
+
+// How often the partial file of a running download is flushed to disk, set by -checkpoint-interval:
+// either after the given time or after the given number of bytes. The zero value disables checkpoints.
+type CheckpointInterval struct {
+	Duration time.Duration
+	Bytes    int64
+}
+
+// Parses the value of -checkpoint-interval, which is either a duration like 30s or a size like 1GB.
+func ParseCheckpointInterval(value string) (CheckpointInterval, error) {
+	value = strings.TrimSpace(value)
+	if duration, err := time.ParseDuration(value); err == nil {
+		if duration <= 0 {
+			return CheckpointInterval{}, errors.New("The checkpoint interval must be positive, e.g. 30s or 1GB")
+		}
+
+		return CheckpointInterval{Duration: duration}, nil
+	}
+
+	size, err := ParseSize(value)
+	if err != nil || size <= 0 {
+		return CheckpointInterval{}, errors.New(fmt.Sprintf("Invalid checkpoint interval '%s'. Use a duration like 30s or a size like 1GB", value))
+	}
+
+	return CheckpointInterval{Bytes: size}, nil
+}
+
+// Returns true if checkpoints are written.
+func (interval CheckpointInterval) Enabled() bool {
+	return interval.Duration > 0 || interval.Bytes > 0
+}
+
+// Writes the partial file of a download and flushes it to disk at every checkpoint, so a download
+// which is resumed after a crash or power loss continues from data which is actually on disk.
+type checkpointWriter struct {
+	file     *os.File
+	interval CheckpointInterval
+	// Size of the file which is known to be on disk.
+	offset int64
+	// Number of bytes written since the last checkpoint.
+	pending int64
+	last    time.Time
+	// Called with the new offset after every checkpoint.
+	onCheckpoint func(offset int64)
+}
+
+// Creates a writer for the given file, which already contains offset bytes.
+func newCheckpointWriter(file *os.File, offset int64, interval CheckpointInterval, onCheckpoint func(offset int64)) *checkpointWriter {
+	return &checkpointWriter{file: file, interval: interval, offset: offset, last: time.Now(), onCheckpoint: onCheckpoint}
+}
+
+func (writer *checkpointWriter) Write(p []byte) (int, error) {
+	n, err := writer.file.Write(p)
+	writer.pending += int64(n)
+	if err != nil {
+		return n, err
+	}
+
+	if (writer.interval.Bytes > 0 && writer.pending >= writer.interval.Bytes) ||
+		(writer.interval.Duration > 0 && time.Since(writer.last) >= writer.interval.Duration) {
+		if err := writer.checkpoint(); err != nil {
+			return n, err
+		}
+	}
+
+	return n, nil
+}
+
+// Flushes everything written so far to disk and records the new offset.
+func (writer *checkpointWriter) checkpoint() error {
+	if writer.pending == 0 {
+		return nil
+	}
+
+	if err := writer.file.Sync(); err != nil {
+		return errors.New(fmt.Sprintf("Failed to flush %s: %s", writer.file.Name(), err))
+	}
+
+	writer.offset += writer.pending
+	writer.pending = 0
+	writer.last = time.Now()
+	if writer.onCheckpoint != nil {
+		writer.onCheckpoint(writer.offset)
+	}
+
+	return nil
+}
@@ -0,0 +1,83 @@
+package jf_requests
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestDownloadFromUrlResumesAtCheckpoint(t *testing.T) {
+	content := bytes.Repeat([]byte("0123456789"), 100)
+	const interruptedAt = 600
+
+	// The first server drops the connection in the middle of the file
+	interrupted := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Length", strconv.Itoa(len(content)))
+		w.Write(content[:interruptedAt])
+		w.(http.Flusher).Flush()
+		panic(http.ErrAbortHandler)
+	}))
+	t.Cleanup(interrupted.Close)
+
+	job := newDownloadJob(t, interrupted.URL, int64(len(content)))
+	manifest := NewManifest(filepath.Join(t.TempDir(), DefaultManifestName), interrupted.URL)
+	if err := manifest.Add([]DownloadJob{job}); err != nil {
+		t.Fatal(err)
+	}
+
+	opts := DownloadOptions{Manifest: manifest, Checkpoint: CheckpointInterval{Bytes: 100}}
+	if _, err := DownloadFromUrl(context.Background(), job, job.Name, false, opts); err == nil {
+		t.Fatal("interrupted download succeeded, want an error")
+	}
+
+	checkpoint, ok := manifest.GetCheckpoint(job.Outfile)
+	if !ok || checkpoint <= 0 || checkpoint > interruptedAt {
+		t.Fatalf("checkpoint is %d (recorded: %v), want one between 1 and %d", checkpoint, ok, interruptedAt)
+	}
+
+	// Simulate a crash: the data written behind the checkpoint never reached the disk and the
+	// partial file ends with garbage instead
+	partfile := GetPartialFile(job.Outfile, "")
+	f, err := os.OpenFile(partfile, os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := f.Write(bytes.Repeat([]byte("X"), 50)); err != nil {
+		t.Fatal(err)
+	}
+	f.Close()
+
+	var mutex sync.Mutex
+	var ranges []string
+	resumed := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mutex.Lock()
+		ranges = append(ranges, r.Header.Get("Range"))
+		mutex.Unlock()
+		http.ServeContent(w, r, "file", time.Time{}, bytes.NewReader(content))
+	}))
+	t.Cleanup(resumed.Close)
+
+	job.Url = resumed.URL
+	written, err := DownloadFromUrl(context.Background(), job, job.Name, false, opts)
+	if err != nil {
+		t.Fatalf("resumed download failed: %s", err)
+	}
+
+	if want := "bytes=" + strconv.FormatInt(checkpoint, 10) + "-"; len(ranges) != 1 || ranges[0] != want {
+		t.Errorf("requested ranges %q, want [%q]", ranges, want)
+	}
+
+	if want := int64(len(content)) - checkpoint; written != want {
+		t.Errorf("transferred %d bytes, want %d", written, want)
+	}
+
+	assertDownloaded(t, job, content)
+}
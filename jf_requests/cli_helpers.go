@@ -4,34 +4,184 @@ import (
 	"bufio"
 	"errors"
 	"fmt"
+	"io"
 	"log/slog"
 	"os"
+	"slices"
 	"strconv"
 	"strings"
+
+	"github.com/fatih/color"
 )
 
+// If set, all confirmations are answered with yes and no prompts are shown.
+var AssumeYes = false
+
+// Error returned when a prompt would be required but prompting is disabled with AssumeYes.
+var ErrPromptDisabled = errors.New("A selection is required but prompts are disabled")
+
+// Error returned when the input ended before a selection was made, e.g. because of Ctrl-D.
+var ErrNoSelection = errors.New("No selection was made")
+
+// Number of times a prompt is repeated after an invalid answer before giving up.
+const maxPromptAttempts = 3
+
+// All prompts share a single reader, otherwise lines which were already buffered by one reader
+// would be lost for the next prompt when the input is piped.
+var stdinReader = bufio.NewReader(os.Stdin)
+
+// Reads a single line from stdin without the line break.
+func ReadLine() (string, error) {
+	line, err := stdinReader.ReadString('\n')
+	if err != nil && (line == "" || err != io.EOF) {
+		return "", err
+	}
+
+	return strings.TrimRight(line, "\r\n"), nil
+}
+
 func GetConfirmation() bool {
-	fmt.Print("Continue? y/n: ")
-	reader := bufio.NewReader(os.Stdin)
-	response, _ := reader.ReadString('\n')
-	response = strings.ToLower(strings.TrimSpace(response))
+	if AssumeYes {
+		fmt.Println("Continue? y/n: y")
+		return true
+	}
+
+	for attempt := 1; attempt <= maxPromptAttempts; attempt += 1 {
+		fmt.Print("Continue? y/n: ")
+		response, err := ReadLine()
+		if err != nil {
+			fmt.Println()
+			return false
+		}
+
+		switch strings.ToLower(strings.TrimSpace(response)) {
+		case "y", "yes":
+			return true
+		case "n", "no":
+			return false
+		}
+
+		color.Red("Please answer with y or n.")
+	}
+
+	return false
+}
+
+// Reads the selection of the user from stdin. Valid selections are all numbers from min_choice
+// up to and including number_of_choices. On invalid input, printMenu is called to show the
+// choices again and the user is asked again, up to maxPromptAttempts times.
+func GetUserChoice(min_choice int, number_of_choices int, printMenu func()) (int, error) {
+	if AssumeYes {
+		return -1, ErrPromptDisabled
+	}
+
+	var err error
+	for attempt := 1; attempt <= maxPromptAttempts; attempt += 1 {
+		if attempt > 1 {
+			color.Red("%s. Please enter a number from %d to %d.", err, min_choice, number_of_choices)
+			printMenu()
+		}
+
+		fmt.Print("==> ")
+		response, readErr := ReadLine()
+		if readErr != nil {
+			fmt.Println()
+			return -1, ErrNoSelection
+		}
+
+		var selection int
+		if selection, err = ParseUserChoice(response, min_choice, number_of_choices); err == nil {
+			return selection, nil
+		}
+	}
 
-	return response == "y"
+	return -1, errors.New(fmt.Sprintf("%s. Giving up after %d attempts", err, maxPromptAttempts))
 }
 
-func GetUserChoice(number_of_choices int) (int, error) {
-	fmt.Print("==> ")
-	reader := bufio.NewReader(os.Stdin)
-	response, _ := reader.ReadString('\n')
-	response = strings.Split(response, "\n")[0]
+// Parses the given selection and checks if it is in the range of min_choice to number_of_choices.
+func ParseUserChoice(response string, min_choice int, number_of_choices int) (int, error) {
+	response = strings.TrimSpace(response)
 	if selection, err := strconv.Atoi(response); err == nil {
-		if selection < 0 || selection > number_of_choices {
+		if selection < min_choice || selection > number_of_choices {
 			return -1, errors.New("Invalid Selection")
 		}
 
 		return selection, nil
 	} else {
-		slog.Error(err.Error())
+		slog.Debug(err.Error())
 		return -1, errors.New("Only provide a single number")
 	}
 }
+
+// Reads a selection of multiple choices from stdin, like GetUserChoice. See ParseUserChoices for
+// the supported format.
+func GetUserChoices(number_of_choices int, printMenu func()) ([]int, error) {
+	if AssumeYes {
+		return nil, ErrPromptDisabled
+	}
+
+	var err error
+	for attempt := 1; attempt <= maxPromptAttempts; attempt += 1 {
+		if attempt > 1 {
+			color.Red("%s. Please enter numbers from 1 to %d like 1,3,5-8.", err, number_of_choices)
+			printMenu()
+		}
+
+		fmt.Print("==> ")
+		response, readErr := ReadLine()
+		if readErr != nil {
+			fmt.Println()
+			return nil, ErrNoSelection
+		}
+
+		var selection []int
+		if selection, err = ParseUserChoices(response, number_of_choices); err == nil {
+			return selection, nil
+		}
+	}
+
+	return nil, errors.New(fmt.Sprintf("%s. Giving up after %d attempts", err, maxPromptAttempts))
+}
+
+// Parses a selection of numbers and ranges like "1,3,5-8" and returns the selected numbers in
+// ascending order. An empty selection selects all numbers from 1 to number_of_choices.
+func ParseUserChoices(response string, number_of_choices int) ([]int, error) {
+	response = strings.TrimSpace(response)
+
+	var selection []int
+	if response == "" {
+		for choice := 1; choice <= number_of_choices; choice++ {
+			selection = append(selection, choice)
+		}
+
+		return selection, nil
+	}
+
+	for _, part := range strings.Split(response, ",") {
+		bounds := strings.SplitN(strings.TrimSpace(part), "-", 2)
+		from, err := strconv.Atoi(strings.TrimSpace(bounds[0]))
+		if err != nil {
+			return nil, errors.New(fmt.Sprintf("Invalid Selection '%s'", strings.TrimSpace(part)))
+		}
+
+		to := from
+		if len(bounds) == 2 {
+			if to, err = strconv.Atoi(strings.TrimSpace(bounds[1])); err != nil {
+				return nil, errors.New(fmt.Sprintf("Invalid Selection '%s'", strings.TrimSpace(part)))
+			}
+		}
+
+		if from < 1 || to > number_of_choices || to < from {
+			return nil, errors.New(fmt.Sprintf("Invalid Selection '%s'", strings.TrimSpace(part)))
+		}
+
+		for choice := from; choice <= to; choice++ {
+			if !slices.Contains(selection, choice) {
+				selection = append(selection, choice)
+			}
+		}
+	}
+
+	slices.Sort(selection)
+	return selection, nil
+}
@@ -0,0 +1,73 @@
+package jf_requests
+
+import (
+	"bufio"
+	"errors"
+	"strings"
+	"testing"
+)
+
+// Replaces stdin of the prompts with the given input for the duration of the test.
+func setStdin(t *testing.T, input string) {
+	t.Helper()
+	previous := stdinReader
+	stdinReader = bufio.NewReader(strings.NewReader(input))
+	t.Cleanup(func() { stdinReader = previous })
+}
+
+func TestGetUserChoice(t *testing.T) {
+	const choices = 3
+	tests := []struct {
+		name      string
+		minChoice int
+		input     string
+		want      int
+		wantErr   bool
+		wantMenus int
+	}{
+		{name: "zero", minChoice: 0, input: "0\n", want: 0},
+		{name: "zero below minimum", minChoice: 1, input: "0\n1\n", want: 1, wantMenus: 1},
+		{name: "negative", minChoice: 0, input: "-1\n2\n", want: 2, wantMenus: 1},
+		{name: "len", minChoice: 1, input: "3\n", want: 3},
+		{name: "len+1", minChoice: 1, input: "4\n1\n", want: 1, wantMenus: 1},
+		{name: "non-numeric", minChoice: 1, input: "two\n2\n", want: 2, wantMenus: 1},
+		{name: "surrounding whitespace", minChoice: 1, input: "  2 \r\n", want: 2},
+		{name: "last line without line break", minChoice: 1, input: "2", want: 2},
+		{name: "gives up", minChoice: 1, input: "-1\n4\nabc\n1\n", want: -1, wantErr: true, wantMenus: 2},
+		{name: "end of input", minChoice: 1, input: "", want: -1, wantErr: true},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			setStdin(t, test.input)
+			menus := 0
+			got, err := GetUserChoice(test.minChoice, choices, func() { menus += 1 })
+			if (err != nil) != test.wantErr {
+				t.Fatalf("GetUserChoice() error = %v, wantErr %v", err, test.wantErr)
+			}
+
+			if got != test.want {
+				t.Errorf("GetUserChoice() = %d, want %d", got, test.want)
+			}
+
+			if menus != test.wantMenus {
+				t.Errorf("menu was printed %d times, want %d", menus, test.wantMenus)
+			}
+		})
+	}
+}
+
+func TestGetUserChoiceEndOfInput(t *testing.T) {
+	setStdin(t, "")
+	if _, err := GetUserChoice(1, 3, func() {}); !errors.Is(err, ErrNoSelection) {
+		t.Errorf("GetUserChoice() error = %v, want %v", err, ErrNoSelection)
+	}
+}
+
+func TestGetUserChoiceAssumeYes(t *testing.T) {
+	AssumeYes = true
+	t.Cleanup(func() { AssumeYes = false })
+	if _, err := GetUserChoice(1, 3, func() {}); !errors.Is(err, ErrPromptDisabled) {
+		t.Errorf("GetUserChoice() error = %v, want %v", err, ErrPromptDisabled)
+	}
+}
@@ -0,0 +1,43 @@
+package jf_requests
+
+import (
+	"log/slog"
+
+	"github.com/fatih/color"
+)
+
+// Checks if the output directory, and the temporary directory if one is set, has enough free space
+// for the given number of bytes and prints a warning if it has not. Returns false if the download should be refused, which is the case if
+// there is not enough space and prompts are disabled, so nobody can decide to download anyway.
+//
+// The sizes of transcoded and remuxed downloads are not known in advance, so they are not checked.
+func CheckDiskSpace(opts DownloadOptions, required int64) bool {
+	if opts.Quality != nil || opts.Container != "" || required <= 0 {
+		return true
+	}
+
+	dirs := []string{opts.OutputDir}
+	if opts.TempDir != "" {
+		dirs = append(dirs, opts.TempDir)
+	}
+
+	enough := true
+	for _, dir := range dirs {
+		if dir == "" {
+			dir = "."
+		}
+
+		free, err := GetFreeSpace(dir)
+		if err != nil {
+			slog.Debug("Failed to determine the free disk space", "dir", dir, "error", err)
+			continue
+		}
+
+		if free < required {
+			color.Red("Not enough free space in %s: %s are required, but only %s are available", dir, FormatBytes(required), FormatBytes(free))
+			enough = false
+		}
+	}
+
+	return enough || !AssumeYes
+}
@@ -0,0 +1,15 @@
+//go:build unix
+
+package jf_requests
+
+import "golang.org/x/sys/unix"
+
+// Returns the number of bytes which can be written to the filesystem of the given directory.
+func GetFreeSpace(dir string) (int64, error) {
+	var stat unix.Statfs_t
+	if err := unix.Statfs(dir, &stat); err != nil {
+		return 0, err
+	}
+
+	return int64(stat.Bavail) * int64(stat.Bsize), nil
+}
@@ -0,0 +1,20 @@
+//go:build windows
+
+package jf_requests
+
+import "golang.org/x/sys/windows"
+
+// Returns the number of bytes which can be written to the filesystem of the given directory.
+func GetFreeSpace(dir string) (int64, error) {
+	path, err := windows.UTF16PtrFromString(dir)
+	if err != nil {
+		return 0, err
+	}
+
+	var available uint64
+	if err := windows.GetDiskFreeSpaceEx(path, &available, nil, nil); err != nil {
+		return 0, err
+	}
+
+	return int64(available), nil
+}
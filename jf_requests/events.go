@@ -0,0 +1,53 @@
+package jf_requests
+
+import (
+	"encoding/json"
+	"io"
+	"sync"
+)
+
+// Writer the JSON events are written to. nil disables the JSON output.
+var eventOutput io.Writer
+
+// Guards the event output, so events of parallel downloads are not interleaved.
+var eventMutex sync.Mutex
+
+// Enables the JSON output mode. Every event is written as a single JSON object per line to the given writer.
+func EnableJsonOutput(writer io.Writer) {
+	eventOutput = writer
+}
+
+func JsonOutputEnabled() bool {
+	return eventOutput != nil
+}
+
+// Writes an event of the given type with the given fields. Does nothing if the JSON output is disabled.
+func EmitEvent(eventType string, fields map[string]any) {
+	if eventOutput == nil {
+		return
+	}
+
+	event := map[string]any{"type": eventType}
+	for key, value := range fields {
+		event[key] = value
+	}
+
+	line, err := json.Marshal(event)
+	if err != nil {
+		return
+	}
+
+	eventMutex.Lock()
+	defer eventMutex.Unlock()
+	eventOutput.Write(append(line, '\n'))
+}
+
+// Emits an item event for a media item which was found on the server.
+func EmitItemEvent(itemType string, id string, name string, fields map[string]any) {
+	event := map[string]any{"item_type": itemType, "id": id, "name": name}
+	for key, value := range fields {
+		event[key] = value
+	}
+
+	EmitEvent("item", event)
+}
@@ -0,0 +1,143 @@
+package jf_requests
+
+import (
+	"errors"
+	"fmt"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+var extensionPattern = regexp.MustCompile(`^[a-z0-9]{1,8}$`)
+
+// Maps the container names and mime types Jellyfin reports to the extension of the file. Besides
+// plain extensions, Jellyfin passes on the format names of ffprobe like "matroska" or "mpegts".
+var containerExtensions = map[string]string{
+	"mkv":              "mkv",
+	"matroska":         "mkv",
+	"video/x-matroska": "mkv",
+	"mk3d":             "mk3d",
+	"webm":             "webm",
+	"video/webm":       "webm",
+	"mp4":              "mp4",
+	"video/mp4":        "mp4",
+	"m4v":              "m4v",
+	"video/x-m4v":      "m4v",
+	"mov":              "mov",
+	"video/quicktime":  "mov",
+	"3gp":              "3gp",
+	"3g2":              "3g2",
+	"ts":               "ts",
+	"mpegts":           "ts",
+	"video/mp2t":       "ts",
+	"m2ts":             "m2ts",
+	"mts":              "m2ts",
+	"avi":              "avi",
+	"video/x-msvideo":  "avi",
+	"wmv":              "wmv",
+	"asf":              "wmv",
+	"video/x-ms-wmv":   "wmv",
+	"flv":              "flv",
+	"video/x-flv":      "flv",
+	"mpg":              "mpg",
+	"mpeg":             "mpg",
+	"video/mpeg":       "mpg",
+	"ogv":              "ogv",
+	"mp3":              "mp3",
+	"audio/mpeg":       "mp3",
+	"flac":             "flac",
+	"audio/flac":       "flac",
+	"m4a":              "m4a",
+	"audio/mp4":        "m4a",
+	"aac":              "aac",
+	"ogg":              "ogg",
+	"audio/ogg":        "ogg",
+	"oga":              "ogg",
+	"opus":             "opus",
+	"wav":              "wav",
+	"audio/wav":        "wav",
+	"wma":              "wma",
+	"ape":              "ape",
+	"wv":               "wv",
+}
+
+// Order in which the extensions of ambiguous containers like "mov,mp4,m4a,3gp,3g2,mj2" are
+// preferred: the formats which are most common for media libraries come first.
+var preferredExtensions = []string{"mkv", "mp4", "webm", "ts", "m4a", "mov", "m4v", "3gp", "3g2"}
+
+// Parses the extension given by -ext, e.g. "mkv" or ".mp4".
+func ParseExtension(extension string) (string, error) {
+	extension = strings.ToLower(strings.TrimPrefix(strings.TrimSpace(extension), "."))
+	if !extensionPattern.MatchString(extension) {
+		return "", errors.New(fmt.Sprintf("Invalid extension '%s', use letters and digits only, e.g. mkv", extension))
+	}
+
+	return extension, nil
+}
+
+// Returns the rank of the extension in preferredExtensions; extensions which are not listed rank last.
+func getExtensionRank(extension string) int {
+	for idx, preferred := range preferredExtensions {
+		if preferred == extension {
+			return idx
+		}
+	}
+
+	return len(preferredExtensions)
+}
+
+// Returns the extensions of a container as reported by Jellyfin, which can be a comma separated
+// list like "mkv,webm" or a mime type. Unknown values are left out.
+func getContainerExtensions(container string) []string {
+	var extensions []string
+	for _, value := range strings.Split(container, ",") {
+		value = strings.ToLower(strings.TrimSpace(value))
+		if mimeType, _, found := strings.Cut(value, ";"); found {
+			value = strings.TrimSpace(mimeType)
+		}
+
+		if extension, ok := containerExtensions[value]; ok {
+			extensions = append(extensions, extension)
+		}
+	}
+
+	return extensions
+}
+
+// Returns the extension of the original file of the item. The extension of the file on the server
+// is used if it is a known media format, since the original file is downloaded as it is. Otherwise
+// the container of the item or its primary source decides; of multiple containers the most common
+// one is chosen.
+func GetMediaExtension(item *MediaItem) string {
+	containers := []string{item.Container}
+	if source := item.GetPrimarySource(); source != nil {
+		if extension, ok := containerExtensions[strings.ToLower(strings.TrimPrefix(filepath.Ext(source.Path), "."))]; ok {
+			return extension
+		}
+
+		containers = append(containers, source.Container)
+	}
+
+	for _, container := range containers {
+		extensions := getContainerExtensions(container)
+		if len(extensions) == 0 {
+			continue
+		}
+
+		selected := extensions[0]
+		for _, extension := range extensions[1:] {
+			if getExtensionRank(extension) < getExtensionRank(selected) {
+				selected = extension
+			}
+		}
+
+		return selected
+	}
+
+	// Unknown containers are kept as they are, as long as they make a valid extension
+	if extension, err := ParseExtension(strings.Split(item.Container, ",")[0]); err == nil {
+		return extension
+	}
+
+	return TranscodeContainer
+}
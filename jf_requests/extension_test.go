@@ -0,0 +1,98 @@
+package jf_requests
+
+import (
+	"path/filepath"
+	"testing"
+)
+
+func TestParseExtension(t *testing.T) {
+	tests := []struct {
+		extension string
+		want      string
+		wantErr   bool
+	}{
+		{extension: "mkv", want: "mkv"},
+		{extension: ".mp4", want: "mp4"},
+		{extension: " TS ", want: "ts"},
+		{extension: "", wantErr: true},
+		{extension: ".", wantErr: true},
+		{extension: "m/kv", wantErr: true},
+		{extension: "toolongext", wantErr: true},
+	}
+
+	for _, test := range tests {
+		got, err := ParseExtension(test.extension)
+		if (err != nil) != test.wantErr {
+			t.Errorf("ParseExtension(%q) error = %v, wantErr %v", test.extension, err, test.wantErr)
+		} else if got != test.want {
+			t.Errorf("ParseExtension(%q) = %q, want %q", test.extension, got, test.want)
+		}
+	}
+}
+
+func TestGetMediaExtension(t *testing.T) {
+	tests := []struct {
+		name      string
+		container string
+		source    *MediaSource
+		want      string
+	}{
+		{name: "mkv", container: "mkv", want: "mkv"},
+		{name: "matroska", container: "matroska", want: "mkv"},
+		{name: "mp4", container: "mp4", want: "mp4"},
+		{name: "ts", container: "ts", want: "ts"},
+		{name: "mpegts", container: "mpegts", want: "ts"},
+		{name: "upper case", container: "MKV", want: "mkv"},
+		{name: "mime type", container: "video/mp4; codecs=avc1", want: "mp4"},
+		{name: "mov family", container: "mov,mp4,m4a,3gp,3g2,mj2", want: "mp4"},
+		{name: "mov family with spaces", container: "mov, m4a ,3gp", want: "m4a"},
+		{name: "matroska family", container: "webm,mkv", want: "mkv"},
+		{name: "unknown values are ignored", container: "mj2,mov", want: "mov"},
+		{name: "unknown container", container: "rmvb", want: "rmvb"},
+		{name: "missing container", container: "", want: TranscodeContainer},
+		{name: "invalid container", container: "not a container", want: TranscodeContainer},
+		{name: "extension of the source", container: "mov,mp4,m4a", source: &MediaSource{Path: "/media/Movie.M4V"}, want: "m4v"},
+		{name: "ts source", container: "mpegts", source: &MediaSource{Path: `D:\media\Show\Episode.ts`}, want: "ts"},
+		{name: "unknown extension of the source", container: "mkv", source: &MediaSource{Path: "/media/Movie.strm"}, want: "mkv"},
+		{name: "container of the source", container: "", source: &MediaSource{Container: "mov,mp4,m4a"}, want: "mp4"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			item := &MediaItem{Name: "Movie", Container: test.container}
+			if test.source != nil {
+				item.MediaSources = []MediaSource{*test.source}
+			}
+
+			if got := GetMediaExtension(item); got != test.want {
+				t.Errorf("GetMediaExtension() = %q, want %q", got, test.want)
+			}
+		})
+	}
+}
+
+func TestGetDownloadJobsExtension(t *testing.T) {
+	tests := []struct {
+		name      string
+		container string
+		opts      DownloadOptions
+		want      string
+	}{
+		{name: "detected mkv", container: "mkv", want: "Movie.mkv"},
+		{name: "detected mp4", container: "mov,mp4,m4a", want: "Movie.mp4"},
+		{name: "detected ts", container: "mpegts", want: "Movie.ts"},
+		{name: "override", container: "mov,mp4,m4a", opts: DownloadOptions{Extension: "mkv"}, want: "Movie.mkv"},
+		{name: "override of ts", container: "ts", opts: DownloadOptions{Extension: "m2ts"}, want: "Movie.m2ts"},
+		{name: "remux container wins over the override", container: "mkv", opts: DownloadOptions{Extension: "ts", Container: "mp4"}, want: "Movie.mp4"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			item := &MediaItem{Name: "Movie", Id: "1", Container: test.container}
+			jobs := item.GetDownloadJobs("http://jellyfin.local", "token", filepath.Join("out", "Movie"), test.opts)
+			if got := filepath.Base(jobs[0].Outfile); got != test.want {
+				t.Errorf("outfile is %q, want %q", got, test.want)
+			}
+		})
+	}
+}
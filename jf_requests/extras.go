@@ -0,0 +1,183 @@
+package jf_requests
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/fatih/color"
+)
+
+// Names of the directories extras are stored in, next to the movie or in the series directory.
+// Trailers have a directory of their own, like Kodi and Jellyfin expect them.
+const (
+	ExtrasDirName   = "extras"
+	TrailersDirName = "trailers"
+)
+
+// A trailer, deleted scene or other extra of a movie.
+type Extra struct {
+	MediaItem
+	// Kind of the extra as reported by Jellyfin, e.g. "Trailer" or "DeletedScene".
+	ExtraType string
+}
+
+// Suffixes Plex uses to recognize extras stored next to the movie.
+var extraSuffixes = map[string]string{
+	"Trailer":         "trailer",
+	"DeletedScene":    "deleted",
+	"BehindTheScenes": "behindthescenes",
+	"Featurette":      "featurette",
+	"Interview":       "interview",
+	"Scene":           "scene",
+	"Short":           "short",
+}
+
+// Fetches the extras and local trailers of the item with the given id. Extras without a media file
+// are skipped.
+func getExtras(ctx context.Context, auth *AuthResponse, baseurl string, id string, name string) ([]Extra, error) {
+	var extras []Extra
+	for _, endpoint := range []string{"SpecialFeatures", "LocalTrailers"} {
+		requestUrl := BuildUrl(baseurl, fmt.Sprintf("Users/%s/Items/%s/%s", auth.UserId, id, endpoint), nil)
+		rawItems, err := MakeListRequest(ctx, auth.Token, requestUrl, "GET", nil)
+		if err != nil {
+			return nil, errors.New(fmt.Sprintf("Failed to obtain the extras of %s: %s", name, err))
+		}
+
+		for _, rawItem := range rawItems {
+			rawExtra := rawItem.(map[string]any)
+			if _, ok := rawExtra["Container"].(string); !ok {
+				continue
+			}
+
+			extra := Extra{MediaItem: GetMediaItemFromRawItem(rawExtra), ExtraType: GetStringFromRawItem(rawExtra, "ExtraType")}
+			if extra.ExtraType == "" && endpoint == "LocalTrailers" {
+				extra.ExtraType = "Trailer"
+			}
+
+			extras = append(extras, extra)
+		}
+	}
+
+	return extras, nil
+}
+
+// Fetches the extras and local trailers of the movie.
+func (movie *Movie) LoadExtras(ctx context.Context, auth *AuthResponse, baseurl string) error {
+	extras, err := getExtras(ctx, auth, baseurl, movie.Id, movie.Name)
+	movie.Extras = extras
+	return err
+}
+
+// Fetches the extras and trailers of the series itself, e.g. a featurette about the whole show.
+func (series *Series) LoadExtras(ctx context.Context, auth *AuthResponse, baseurl string) error {
+	extras, err := getExtras(ctx, auth, baseurl, series.Id, series.Name)
+	series.Extras = extras
+	return err
+}
+
+// Returns the combined size of the given extras.
+func getExtrasSize(extras []Extra) int64 {
+	var total int64 = 0
+	for _, extra := range extras {
+		total += extra.Size
+	}
+
+	return total
+}
+
+// Prints the number and size of the extras which are downloaded, or that there are none.
+func printExtras(extras []Extra) {
+	if len(extras) == 0 {
+		color.Yellow("Extras: none found")
+		return
+	}
+
+	color.Green("Extras: %d (%s)", len(extras), formatSize(getExtrasSize(extras)))
+}
+
+// Returns the media items of the given extras.
+func GetExtraItems(extras []Extra) []*MediaItem {
+	var items []*MediaItem
+	for idx := range extras {
+		items = append(items, &extras[idx].MediaItem)
+	}
+
+	return items
+}
+
+// Returns the path of the extra without extension. Extras are stored in the extras directory next
+// to the movie, trailers in the trailers directory. If flatten is set, they are stored next to the
+// movie instead and named like "Movie-Name of the Extra-trailer", so Plex recognizes them. The
+// extras of a series are passed a movieOutfile inside the series directory named after the series.
+func (extra *Extra) getOutfile(movieOutfile string, layout Layout, flatten bool) string {
+	if flatten {
+		suffix, ok := extraSuffixes[extra.ExtraType]
+		if !ok {
+			suffix = "other"
+		}
+
+		return fmt.Sprintf("%s-%s-%s", movieOutfile, SanitizeFilename(extra.Name), suffix)
+	}
+
+	dir := filepath.Join(filepath.Dir(movieOutfile), ExtrasDirName)
+	if extra.ExtraType == "Trailer" {
+		dir = filepath.Join(filepath.Dir(movieOutfile), TrailersDirName)
+	}
+
+	if layout == LayoutFlat || layout == "" {
+		// Without a directory per movie, the extras of all movies would end up in the same directory
+		dir = filepath.Join(dir, filepath.Base(movieOutfile))
+	}
+
+	return filepath.Join(dir, SanitizeFilename(extra.Name))
+}
+
+// Returns the jobs of the extras of the series, which are stored in the series directory.
+func getSeriesExtraJobs(baseUrl string, token string, series *Series, opts DownloadOptions) []DownloadJob {
+	seriesOutfile := filepath.Join(GetOutputPath(opts.OutputDir, opts.Layout.GetSeriesDir(series)), getNameWithYear(series.Name, series.Year))
+
+	var jobs []DownloadJob
+	for _, extra := range series.Extras {
+		jobs = append(jobs, extra.GetDownloadJobs(baseUrl, token, extra.getOutfile(seriesOutfile, opts.Layout, opts.FlattenExtras), opts)...)
+	}
+
+	return jobs
+}
+
+// Returns a short description of the version, e.g. "2: Director's Cut (12.3 GB)".
+func formatVersion(idx int, source MediaSource) string {
+	return fmt.Sprintf("%d: %s (%s)", idx+1, source.Name, formatSize(source.Size))
+}
+
+// Selects the version of the movie which is downloaded instead of the primary one. wanted is
+// either the position of the version, starting at 1, or a part of its name, e.g. "1080p".
+func (movie *Movie) UseVersion(wanted string) error {
+	var versions []string
+	for idx, source := range movie.MediaSources {
+		versions = append(versions, formatVersion(idx, source))
+	}
+
+	// Numbers like 1080 which are no valid position are matched against the names
+	selected := -1
+	if position, err := strconv.Atoi(wanted); err == nil && position >= 1 && position <= len(movie.MediaSources) {
+		selected = position - 1
+	} else {
+		for idx, source := range movie.MediaSources {
+			if strings.Contains(strings.ToLower(source.Name), strings.ToLower(wanted)) {
+				selected = idx
+				break
+			}
+		}
+	}
+
+	if selected < 0 {
+		return errors.New(fmt.Sprintf("Version '%s' of %s not found. Available versions: %s", wanted, movie.Name, strings.Join(versions, ", ")))
+	}
+
+	movie.useSourceAt(selected)
+	return nil
+}
@@ -0,0 +1,171 @@
+package jf_requests
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"time"
+)
+
+// Maximum length of a path on Windows without the extended-length prefix.
+const windowsMaxPath = 260
+
+// Names which Windows reserves for devices, regardless of the extension.
+var windowsReservedNames = map[string]bool{
+	"CON": true, "PRN": true, "AUX": true, "NUL": true,
+	"COM1": true, "COM2": true, "COM3": true, "COM4": true, "COM5": true, "COM6": true, "COM7": true, "COM8": true, "COM9": true,
+	"LPT1": true, "LPT2": true, "LPT3": true, "LPT4": true, "LPT5": true, "LPT6": true, "LPT7": true, "LPT8": true, "LPT9": true,
+}
+
+// Returns a path component which is valid on Windows. Trailing dots and spaces are removed and
+// reserved device names are suffixed with an underscore, e.g. CON.mkv becomes CON_.mkv.
+func sanitizeWindowsComponent(component string) string {
+	if component == "." || component == ".." {
+		return component
+	}
+
+	component = strings.TrimRight(component, ". ")
+	if component == "" {
+		return "_"
+	}
+
+	base, extension, _ := strings.Cut(component, ".")
+	if windowsReservedNames[strings.ToUpper(strings.TrimRight(base, " "))] {
+		component = base + "_"
+		if extension != "" {
+			component += "." + extension
+		}
+	}
+
+	return component
+}
+
+// Converts the given path into a path which can be written on Windows. Reserved names are renamed
+// and paths which exceed the maximum length get the \\?\ prefix. The path is left untouched on
+// every other platform.
+func GetFilesystemPath(path string) string {
+	if runtime.GOOS != "windows" {
+		return path
+	}
+
+	return getWindowsPath(path)
+}
+
+func getWindowsPath(path string) string {
+	volume := filepath.VolumeName(path)
+	components := strings.Split(filepath.ToSlash(path[len(volume):]), "/")
+	for idx, component := range components {
+		if component != "" {
+			components[idx] = sanitizeWindowsComponent(component)
+		}
+	}
+
+	path = volume + filepath.FromSlash(strings.Join(components, "/"))
+	if len(path) < windowsMaxPath || strings.HasPrefix(path, `\\?\`) {
+		return path
+	}
+
+	// The extended-length prefix requires an absolute path with backslashes
+	if absolute, err := filepath.Abs(path); err == nil {
+		path = absolute
+	}
+
+	if strings.HasPrefix(path, `\\`) {
+		return `\\?\UNC\` + strings.TrimPrefix(path, `\\`)
+	}
+
+	return `\\?\` + path
+}
+
+// Returns the newest modification time of all files inside dir and its subdirectories. Partial
+// downloads and the manifest are ignored. Returns the zero time if dir contains no files.
+func GetNewestModTime(dir string) (time.Time, error) {
+	if dir == "" {
+		dir = "."
+	}
+
+	var newest time.Time
+	err := filepath.WalkDir(dir, func(path string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+
+		name := entry.Name()
+		if entry.IsDir() || strings.HasSuffix(name, PartialFileSuffix) || strings.HasPrefix(name, DefaultManifestName) {
+			return nil
+		}
+
+		info, err := entry.Info()
+		if err != nil {
+			return err
+		}
+
+		if info.ModTime().After(newest) {
+			newest = info.ModTime()
+		}
+
+		return nil
+	})
+
+	if err != nil {
+		return time.Time{}, errors.New(fmt.Sprintf("Failed to search %s for existing files: %s", dir, err))
+	}
+
+	return newest, nil
+}
+
+// Moves the file from one path to another. Files can not be renamed across filesystems, e.g. from
+// a local temporary directory to a network mount; they are copied next to the target under a
+// temporary name and renamed there instead, so the target still appears at once.
+func MoveFile(from string, to string) error {
+	err := os.Rename(from, to)
+	if err == nil || !isCrossDeviceError(err) {
+		return err
+	}
+
+	slog.Debug(fmt.Sprintf("%s is on another filesystem than %s, copying it", from, to))
+	tmpfile := to + PartialFileSuffix
+	if err := copyFile(from, tmpfile); err != nil {
+		os.Remove(tmpfile)
+		return err
+	}
+
+	if err := os.Rename(tmpfile, to); err != nil {
+		os.Remove(tmpfile)
+		return err
+	}
+
+	return os.Remove(from)
+}
+
+// Copies the content of the file to a new file, which is flushed to disk before it is closed.
+func copyFile(from string, to string) error {
+	source, err := os.Open(from)
+	if err != nil {
+		return err
+	}
+	defer source.Close()
+
+	target, err := os.OpenFile(to, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0644)
+	if err != nil {
+		return err
+	}
+
+	if _, err := io.Copy(target, source); err != nil {
+		target.Close()
+		return err
+	}
+
+	if err := target.Sync(); err != nil {
+		target.Close()
+		return err
+	}
+
+	return target.Close()
+}
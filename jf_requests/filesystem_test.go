@@ -0,0 +1,78 @@
+package jf_requests
+
+import (
+	"runtime"
+	"strings"
+	"testing"
+)
+
+func TestSanitizeWindowsComponent(t *testing.T) {
+	tests := []struct {
+		component string
+		want      string
+	}{
+		{"CON", "CON_"},
+		{"NUL.txt", "NUL_.txt"},
+		{"COM1", "COM1_"},
+		{"LPT9.mkv", "LPT9_.mkv"},
+		{"con", "con_"},
+		{"nul.TXT", "nul_.TXT"},
+		{"Com1.mkv", "Com1_.mkv"},
+		{"CON.tar.gz", "CON_.tar.gz"},
+		{"CON.", "CON_"},
+		{"CON ", "CON_"},
+		{"NUL. .", "NUL_"},
+		{"Episode.", "Episode"},
+		{"Episode ", "Episode"},
+		{"...", "_"},
+		{"COM10", "COM10"},
+		{"CONSOLE.mkv", "CONSOLE.mkv"},
+		{"Icon.png", "Icon.png"},
+		{".", "."},
+		{"..", ".."},
+	}
+
+	for _, test := range tests {
+		if got := sanitizeWindowsComponent(test.component); got != test.want {
+			t.Errorf("sanitizeWindowsComponent(%q) = %q, want %q", test.component, got, test.want)
+		}
+	}
+}
+
+func TestGetFilesystemPathOtherPlatforms(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("paths are converted on Windows")
+	}
+
+	path := "out/CON/" + strings.Repeat("a", windowsMaxPath) + ".mkv"
+	if got := GetFilesystemPath(path); got != path {
+		t.Errorf("GetFilesystemPath(%q) = %q, want the path unchanged", path, got)
+	}
+}
+
+func TestGetWindowsPath(t *testing.T) {
+	if runtime.GOOS != "windows" {
+		t.Skip("volumes and separators are only parsed like this on Windows")
+	}
+
+	long := strings.Repeat("a", windowsMaxPath)
+	tests := []struct {
+		name string
+		path string
+		want string
+	}{
+		{"short path", `C:\Media\Show\Episode.mkv`, `C:\Media\Show\Episode.mkv`},
+		{"reserved names", `C:\Media\CON\nul.mkv.`, `C:\Media\CON_\nul_.mkv`},
+		{"long path", `C:\Media\` + long + `.mkv`, `\\?\C:\Media\` + long + `.mkv`},
+		{"long UNC path", `\\server\share\` + long + `.mkv`, `\\?\UNC\server\share\` + long + `.mkv`},
+		{"prefixed path", `\\?\C:\Media\` + long + `.mkv`, `\\?\C:\Media\` + long + `.mkv`},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if got := getWindowsPath(test.path); got != test.want {
+				t.Errorf("getWindowsPath(%q) = %q, want %q", test.path, got, test.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,342 @@
+package jf_requests
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Position of an episode within a series. A season of -1 matches episodes of every season.
+type EpisodeCoordinate struct {
+	Season  int
+	Episode int
+}
+
+// An inclusive range of episodes.
+type EpisodeRange struct {
+	From EpisodeCoordinate
+	To   EpisodeCoordinate
+}
+
+// A set of episode ranges, as given by the -episodes flag.
+type EpisodeSelection []EpisodeRange
+
+var episodeCoordinatePattern = regexp.MustCompile(`^(?:[Ss](\d+))?[Ee]?(\d+)$`)
+
+func parseEpisodeCoordinate(expr string) (EpisodeCoordinate, error) {
+	match := episodeCoordinatePattern.FindStringSubmatch(expr)
+	if match == nil {
+		return EpisodeCoordinate{}, errors.New(fmt.Sprintf("Invalid episode '%s'. Use a number like 5 or S01E05", expr))
+	}
+
+	coordinate := EpisodeCoordinate{Season: -1}
+	if match[1] != "" {
+		coordinate.Season, _ = strconv.Atoi(match[1])
+	}
+
+	coordinate.Episode, _ = strconv.Atoi(match[2])
+	return coordinate, nil
+}
+
+// Parses the episode given by -since-episode, e.g. S03E07. Unlike in episode selections, the season
+// is required, since the episode numbers start over in every season.
+func ParseEpisodeMarker(expr string) (EpisodeCoordinate, error) {
+	coordinate, err := parseEpisodeCoordinate(strings.TrimSpace(expr))
+	if err != nil || coordinate.Season == -1 {
+		return EpisodeCoordinate{}, errors.New(fmt.Sprintf("Invalid episode '%s'. Use the season and episode like S03E07", expr))
+	}
+
+	return coordinate, nil
+}
+
+// Parses an episode selection like "5", "1,3,5", "3-8", "3-" or "S01E03-S01E08".
+func ParseEpisodeSelection(expr string) (EpisodeSelection, error) {
+	var selection EpisodeSelection
+
+	for _, part := range strings.Split(expr, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			return nil, errors.New(fmt.Sprintf("Invalid episode selection '%s': empty entry", expr))
+		}
+
+		bounds := strings.SplitN(part, "-", 2)
+		from, err := parseEpisodeCoordinate(bounds[0])
+		if err != nil {
+			return nil, err
+		}
+
+		to := from
+		if len(bounds) == 2 {
+			if bounds[1] == "" {
+				to = EpisodeCoordinate{Season: from.Season, Episode: math.MaxInt}
+				if from.Season != -1 {
+					to.Season = math.MaxInt
+				}
+			} else if to, err = parseEpisodeCoordinate(bounds[1]); err != nil {
+				return nil, err
+			}
+		}
+
+		// A range like S01E03-E08 stays within the season of its start
+		if from.Season != -1 && to.Season == -1 {
+			to.Season = from.Season
+		} else if from.Season == -1 && to.Season != -1 {
+			return nil, errors.New(fmt.Sprintf("Invalid episode range '%s': the start must contain the season as well", part))
+		}
+
+		if to.isBefore(from) {
+			return nil, errors.New(fmt.Sprintf("Invalid episode range '%s': the end is before the start", part))
+		}
+
+		selection = append(selection, EpisodeRange{From: from, To: to})
+	}
+
+	return selection, nil
+}
+
+func (coordinate EpisodeCoordinate) isBefore(other EpisodeCoordinate) bool {
+	if coordinate.Season != other.Season {
+		return coordinate.Season < other.Season
+	}
+
+	return coordinate.Episode < other.Episode
+}
+
+// Checks if the episode with the given season and episode number comes strictly after the coordinate.
+func (coordinate EpisodeCoordinate) Precedes(season int, episode int) bool {
+	return coordinate.isBefore(EpisodeCoordinate{Season: season, Episode: episode})
+}
+
+// Checks if the episode with the given season and episode number is part of the selection.
+func (selection EpisodeSelection) Matches(season int, episode int) bool {
+	for _, episodeRange := range selection {
+		position := EpisodeCoordinate{Season: season, Episode: episode}
+		if episodeRange.From.Season == -1 {
+			position.Season = -1
+		}
+
+		if !position.isBefore(episodeRange.From) && !episodeRange.To.isBefore(position) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Returns copies of the given seasons which only contain the episodes for which keep returns true.
+// The missing episodes are filtered the same way. Seasons without any remaining episodes or missing
+// episodes are dropped.
+func FilterEpisodes(seasons []Season, keep func(season *Season, episode *Episode) bool) []Season {
+	var result []Season
+	for _, season := range seasons {
+		filtered := season
+		filtered.Episodes = nil
+		filtered.Missing = nil
+
+		for _, episode := range season.Episodes {
+			if keep(&season, &episode) {
+				filtered.Episodes = append(filtered.Episodes, episode)
+			}
+		}
+
+		for _, episode := range season.Missing {
+			if keep(&season, &episode) {
+				filtered.Missing = append(filtered.Missing, episode)
+			}
+		}
+
+		if len(filtered.Episodes) > 0 || len(filtered.Missing) > 0 {
+			result = append(result, filtered)
+		}
+	}
+
+	return result
+}
+
+// Genres and tags which items have to match, as given by -genre and -tag. An item matches if it has
+// at least one of the genres and at least one of the tags, ignoring the case. Empty lists match
+// every item.
+type MetadataFilter struct {
+	Genres []string
+	Tags   []string
+}
+
+func (filter MetadataFilter) IsEmpty() bool {
+	return len(filter.Genres) == 0 && len(filter.Tags) == 0
+}
+
+// Checks if one of the values is contained in the wanted values. Every value matches if nothing is wanted.
+func containsAnyFold(values []string, wanted []string) bool {
+	if len(wanted) == 0 {
+		return true
+	}
+
+	for _, value := range values {
+		for _, want := range wanted {
+			if strings.EqualFold(value, want) {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+// Checks if the item has one of the wanted genres and one of the wanted tags.
+func (filter MetadataFilter) Matches(item *Item) bool {
+	return containsAnyFold(item.Genres, filter.Genres) && containsAnyFold(item.Tags, filter.Tags)
+}
+
+// Returns the items which match the filter.
+func FilterItems(items []Item, filter MetadataFilter) []Item {
+	if filter.IsEmpty() {
+		return items
+	}
+
+	var result []Item
+	for _, item := range items {
+		if filter.Matches(&item) {
+			result = append(result, item)
+		}
+	}
+
+	return result
+}
+
+var sizePattern = regexp.MustCompile(`^(\d+(?:\.\d+)?)\s*([a-zA-Z]*)$`)
+
+// Number of bytes for each supported size unit.
+var sizeUnits = map[string]float64{
+	"":    1,
+	"b":   1,
+	"k":   1_000,
+	"kb":  1_000,
+	"m":   1_000_000,
+	"mb":  1_000_000,
+	"g":   1_000_000_000,
+	"gb":  1_000_000_000,
+	"t":   1_000_000_000_000,
+	"tb":  1_000_000_000_000,
+	"kib": 1024,
+	"mib": 1024 * 1024,
+	"gib": 1024 * 1024 * 1024,
+	"tib": 1024 * 1024 * 1024 * 1024,
+}
+
+// Parses a size like 500MB, 8GB or 1.5GiB and returns it in bytes.
+func ParseSize(value string) (int64, error) {
+	match := sizePattern.FindStringSubmatch(strings.TrimSpace(value))
+	if match == nil {
+		return 0, errors.New(fmt.Sprintf("Invalid size '%s'. Use a size like 500MB, 8GB or 1.5GiB", value))
+	}
+
+	factor, ok := sizeUnits[strings.ToLower(match[2])]
+	if !ok {
+		return 0, errors.New(fmt.Sprintf("Unknown unit '%s' in size '%s'", match[2], value))
+	}
+
+	number, _ := strconv.ParseFloat(match[1], 64)
+	return int64(number * factor), nil
+}
+
+var relativeDatePattern = regexp.MustCompile(`^(\d+)\s*([hdw])$`)
+
+// Parses the value of the -since flag. It is either a date like 2024-05-31, a timestamp in the RFC
+// 3339 format or a duration relative to now like 12h, 7d or 2w.
+func ParseSince(value string, now time.Time) (time.Time, error) {
+	value = strings.TrimSpace(value)
+
+	if match := relativeDatePattern.FindStringSubmatch(strings.ToLower(value)); match != nil {
+		amount, _ := strconv.Atoi(match[1])
+		unit := map[string]time.Duration{"h": time.Hour, "d": 24 * time.Hour, "w": 7 * 24 * time.Hour}[match[2]]
+		return now.Add(-time.Duration(amount) * unit), nil
+	}
+
+	if date, err := time.ParseInLocation(time.DateOnly, value, time.Local); err == nil {
+		return date, nil
+	}
+
+	if date, err := time.Parse(time.RFC3339, value); err == nil {
+		return date, nil
+	}
+
+	return time.Time{}, errors.New(fmt.Sprintf("Invalid date '%s'. Use a date like 2024-05-31 or a relative time like 12h, 7d or 2w", value))
+}
+
+// Which episodes are downloaded depending on whether the user has watched them.
+type WatchedMode string
+
+const (
+	WatchedAll       WatchedMode = "all"
+	WatchedOnly      WatchedMode = "watched"
+	WatchedUnwatched WatchedMode = "unwatched"
+)
+
+func ParseWatchedMode(mode string) (WatchedMode, error) {
+	switch WatchedMode(strings.ToLower(mode)) {
+	case "", WatchedAll:
+		return WatchedAll, nil
+	case WatchedOnly, WatchedUnwatched:
+		return WatchedMode(strings.ToLower(mode)), nil
+	}
+
+	return "", errors.New(fmt.Sprintf("Unknown value '%s' for -watched. Use all, watched or unwatched", mode))
+}
+
+// Checks if an episode with the given played state is downloaded with the mode.
+func (mode WatchedMode) Matches(played bool) bool {
+	switch mode {
+	case WatchedOnly:
+		return played
+	case WatchedUnwatched:
+		return !played
+	}
+
+	return true
+}
+
+// Whether the specials of a series are downloaded.
+type SpecialsMode string
+
+const (
+	SpecialsInclude SpecialsMode = "include"
+	SpecialsExclude SpecialsMode = "exclude"
+	SpecialsOnly    SpecialsMode = "only"
+)
+
+func ParseSpecialsMode(mode string) (SpecialsMode, error) {
+	switch SpecialsMode(strings.ToLower(mode)) {
+	case "", SpecialsInclude:
+		return SpecialsInclude, nil
+	case SpecialsExclude, SpecialsOnly:
+		return SpecialsMode(strings.ToLower(mode)), nil
+	}
+
+	return "", errors.New(fmt.Sprintf("Unknown value '%s' for -specials. Use include, exclude or only", mode))
+}
+
+// Checks if the season contains the specials of the series. Jellyfin stores them as season 0,
+// which is usually named "Specials".
+func (season *Season) IsSpecial() bool {
+	return season.Index == 0 || strings.EqualFold(season.Name, "Specials")
+}
+
+// Returns the seasons which are kept with the given mode.
+func FilterSpecials(seasons []Season, mode SpecialsMode) []Season {
+	if mode == SpecialsInclude || mode == "" {
+		return seasons
+	}
+
+	var result []Season
+	for _, season := range seasons {
+		if season.IsSpecial() == (mode == SpecialsOnly) {
+			result = append(result, season)
+		}
+	}
+
+	return result
+}
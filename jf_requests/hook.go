@@ -0,0 +1,258 @@
+package jf_requests
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"os/exec"
+	"path"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// Placeholders which can be used in the arguments of a command hook. Like in filename templates,
+// numeric placeholders support a width specifier like {season:02d}.
+var hookPlaceholders = map[string]bool{
+	"path":    false,
+	"name":    false,
+	"series":  false,
+	"season":  true,
+	"episode": true,
+	"title":   false,
+	"year":    true,
+}
+
+// Shells whose script is given as argument of the option which matches the pattern, e.g. sh -c.
+var shellScriptOptions = map[string]*regexp.Regexp{
+	"sh":         regexp.MustCompile(`^-[a-zA-Z]*c[a-zA-Z]*$`),
+	"bash":       regexp.MustCompile(`^-[a-zA-Z]*c[a-zA-Z]*$`),
+	"zsh":        regexp.MustCompile(`^-[a-zA-Z]*c[a-zA-Z]*$`),
+	"dash":       regexp.MustCompile(`^-[a-zA-Z]*c[a-zA-Z]*$`),
+	"ksh":        regexp.MustCompile(`^-[a-zA-Z]*c[a-zA-Z]*$`),
+	"ash":        regexp.MustCompile(`^-[a-zA-Z]*c[a-zA-Z]*$`),
+	"fish":       regexp.MustCompile(`^-[a-zA-Z]*c[a-zA-Z]*$`),
+	"cmd":        regexp.MustCompile(`(?i)^/[ck]$`),
+	"powershell": regexp.MustCompile(`(?i)^-(c|command)$`),
+	"pwsh":       regexp.MustCompile(`(?i)^-(c|command)$`),
+}
+
+// A command which is executed after every downloaded episode or movie, e.g. "notify-send {name}".
+// The command is executed directly without a shell, so the substituted values can not inject
+// further commands. Shell scripts like sh -c '...' must not contain placeholders, since the
+// titles on the server would be interpreted by the shell; they read the values from the
+// environment variables of getEnvironment instead.
+type CommandHook struct {
+	args []string
+	// Stop the remaining downloads if the command fails.
+	abortOnFailure bool
+	mutex          sync.Mutex
+	failed         error
+}
+
+// Splits the command line into its arguments. Arguments can be quoted with single or double quotes;
+// inside double quotes, backslashes escape quotes and backslashes.
+func splitCommandLine(command string) ([]string, error) {
+	var args []string
+	var current strings.Builder
+	inArgument := false
+	var quote rune
+
+	runes := []rune(command)
+	for idx := 0; idx < len(runes); idx++ {
+		char := runes[idx]
+		switch {
+		case quote == '\'':
+			if char == '\'' {
+				quote = 0
+			} else {
+				current.WriteRune(char)
+			}
+		case quote == '"':
+			if char == '"' {
+				quote = 0
+			} else if char == '\\' && idx+1 < len(runes) && (runes[idx+1] == '"' || runes[idx+1] == '\\') {
+				idx++
+				current.WriteRune(runes[idx])
+			} else {
+				current.WriteRune(char)
+			}
+		case char == '\'' || char == '"':
+			quote = char
+			inArgument = true
+		case char == ' ' || char == '\t':
+			if inArgument {
+				args = append(args, current.String())
+				current.Reset()
+				inArgument = false
+			}
+		default:
+			current.WriteRune(char)
+			inArgument = true
+		}
+	}
+
+	if quote != 0 {
+		return nil, errors.New(fmt.Sprintf("Unterminated quote in command '%s'", command))
+	}
+
+	if inArgument {
+		args = append(args, current.String())
+	}
+
+	return args, nil
+}
+
+// Parses the given command line. Unknown placeholders result in an error.
+func ParseCommandHook(command string, abortOnFailure bool) (*CommandHook, error) {
+	args, err := splitCommandLine(command)
+	if err != nil {
+		return nil, err
+	} else if len(args) == 0 {
+		return nil, errors.New("The command must not be empty")
+	}
+
+	for _, match := range templatePlaceholderPattern.FindAllStringSubmatch(command, -1) {
+		numeric, known := hookPlaceholders[match[1]]
+		if !known {
+			return nil, errors.New(fmt.Sprintf("Unknown placeholder {%s} in command. Known placeholders are {path}, {name}, {series}, {season}, {episode}, {title} and {year}", match[1]))
+		}
+
+		if match[3] != "" && !numeric {
+			return nil, errors.New(fmt.Sprintf("Placeholder {%s} does not support a width specifier", match[1]))
+		}
+	}
+
+	// Commands can be given as Windows paths like C:\Windows\System32\cmd.exe on every platform
+	shell := strings.TrimSuffix(strings.ToLower(path.Base(strings.ReplaceAll(args[0], `\`, "/"))), ".exe")
+	if option, ok := shellScriptOptions[shell]; ok {
+		for idx := 1; idx < len(args); idx++ {
+			if option.MatchString(args[idx-1]) && templatePlaceholderPattern.MatchString(args[idx]) {
+				return nil, errors.New(fmt.Sprintf("Placeholders can not be used in the script of %s, since the shell would interpret the substituted titles. Use the environment variables like $JF_TITLE instead", args[0]))
+			}
+		}
+	}
+
+	return &CommandHook{args: args, abortOnFailure: abortOnFailure}, nil
+}
+
+// Returns the values of the placeholders for the given job.
+func getHookValues(job DownloadJob) TemplateValues {
+	if job.Episode != nil {
+		return *job.Episode
+	}
+
+	return TemplateValues{Title: job.Name, Year: job.Year}
+}
+
+// Returns the environment of the command for the given job: the environment of this process and
+// the values of the placeholders as JF_PATH, JF_NAME, JF_SERIES, JF_SEASON, JF_EPISODE, JF_TITLE
+// and JF_YEAR. Numbers which are unknown are empty.
+func getEnvironment(job DownloadJob) []string {
+	values := getHookValues(job)
+	number := func(value int) string {
+		if value == 0 && job.Episode == nil {
+			return ""
+		}
+
+		return strconv.Itoa(value)
+	}
+
+	year := ""
+	if values.Year != 0 {
+		year = strconv.Itoa(values.Year)
+	}
+
+	return append(os.Environ(),
+		"JF_PATH="+job.Outfile,
+		"JF_NAME="+job.Name,
+		"JF_SERIES="+values.Series,
+		"JF_SEASON="+number(values.Season),
+		"JF_EPISODE="+number(values.Episode),
+		"JF_TITLE="+values.Title,
+		"JF_YEAR="+year,
+	)
+}
+
+// Returns the arguments of the command for the given job with all placeholders replaced.
+func (hook *CommandHook) getArguments(job DownloadJob) []string {
+	values := getHookValues(job)
+	args := make([]string, len(hook.args))
+	for idx, arg := range hook.args {
+		args[idx] = templatePlaceholderPattern.ReplaceAllStringFunc(arg, func(placeholder string) string {
+			match := templatePlaceholderPattern.FindStringSubmatch(placeholder)
+
+			var number int
+			switch match[1] {
+			case "path":
+				return job.Outfile
+			case "name":
+				return job.Name
+			case "series":
+				return values.Series
+			case "title":
+				return values.Title
+			case "season":
+				number = values.Season
+			case "episode":
+				number = values.Episode
+			case "year":
+				if values.Year == 0 {
+					return ""
+				}
+
+				number = values.Year
+			}
+
+			return formatNumber(number, match[2] == "0", match[3])
+		})
+	}
+
+	return args
+}
+
+// Executes the command for the downloaded file of the given job. The output of the command is
+// written to stderr, so it does not interfere with the JSON output.
+func (hook *CommandHook) Run(ctx context.Context, job DownloadJob) error {
+	args := hook.getArguments(job)
+	slog.Debug(fmt.Sprintf("Running command for %s", job.Name), "args", args)
+
+	cmd := exec.CommandContext(ctx, args[0], args[1:]...)
+	cmd.Env = getEnvironment(job)
+	cmd.Stdout = os.Stderr
+	cmd.Stderr = os.Stderr
+	if err := cmd.Run(); err != nil {
+		err = errors.New(fmt.Sprintf("Command %s for %s failed: %s", args[0], job.Name, err))
+		if hook.abortOnFailure {
+			hook.mutex.Lock()
+			hook.failed = err
+			hook.mutex.Unlock()
+		}
+
+		return err
+	}
+
+	return nil
+}
+
+// Returns the error of the command if it failed and the remaining downloads should be stopped.
+// Returns nil if no hook is configured.
+func (hook *CommandHook) Failed() error {
+	if hook == nil {
+		return nil
+	}
+
+	hook.mutex.Lock()
+	defer hook.mutex.Unlock()
+
+	return hook.failed
+}
+
+// Checks if the command should be executed for the given job. Sidecar files like subtitles or
+// artwork do not trigger the command.
+func (hook *CommandHook) appliesTo(job DownloadJob) bool {
+	return hook != nil && job.Content == nil && (job.Media != nil || job.Episode != nil)
+}
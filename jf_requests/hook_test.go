@@ -0,0 +1,76 @@
+package jf_requests
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestParseCommandHookShellScripts(t *testing.T) {
+	tests := []struct {
+		command string
+		wantErr bool
+	}{
+		{command: `notify-send {name}`},
+		{command: `ffmpeg -i {path} -c copy {path}.mp4`},
+		{command: `sh -c 'echo "$JF_TITLE" >> downloaded.txt'`},
+		{command: `sh -c 'echo {title} | tee -a downloaded.txt'`, wantErr: true},
+		{command: `/bin/bash -ec "echo {path}"`, wantErr: true},
+		{command: `sh -c 'notify-send "$1"' sh {title}`},
+		{command: `C:\Windows\System32\cmd.exe /C "echo {title}"`, wantErr: true},
+		{command: `pwsh -Command "Write-Output {title}"`, wantErr: true},
+	}
+
+	for _, test := range tests {
+		if _, err := ParseCommandHook(test.command, false); (err != nil) != test.wantErr {
+			t.Errorf("ParseCommandHook(%q) error = %v, wantErr %v", test.command, err, test.wantErr)
+		}
+	}
+}
+
+func TestCommandHookValues(t *testing.T) {
+	hook, err := ParseCommandHook(`sh -c "echo \"$JF_TITLE\" >> downloaded.txt" {title} {year} S{season:02d}E{episode:02d}`, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name    string
+		job     DownloadJob
+		wantArg []string
+		wantEnv []string
+	}{
+		{
+			name:    "episode",
+			job:     DownloadJob{Name: "Pilot", Outfile: "out/Show/Pilot.mkv", Episode: &TemplateValues{Series: "Show", Season: 1, Episode: 2, Title: "Pilot", Year: 2004}},
+			wantArg: []string{"sh", "-c", `echo "$JF_TITLE" >> downloaded.txt`, "Pilot", "2004", "S01E02"},
+			wantEnv: []string{"JF_PATH=out/Show/Pilot.mkv", "JF_NAME=Pilot", "JF_SERIES=Show", "JF_SEASON=1", "JF_EPISODE=2", "JF_TITLE=Pilot", "JF_YEAR=2004"},
+		},
+		{
+			name:    "special",
+			job:     DownloadJob{Name: "Special", Outfile: "out/Show/Special.mkv", Episode: &TemplateValues{Series: "Show", Season: 0, Episode: 1, Title: "Special"}},
+			wantArg: []string{"sh", "-c", `echo "$JF_TITLE" >> downloaded.txt`, "Special", "", "S00E01"},
+			wantEnv: []string{"JF_SEASON=0", "JF_EPISODE=1", "JF_YEAR="},
+		},
+		{
+			name:    "movie",
+			job:     DownloadJob{Name: "Movie; rm -rf ~", Outfile: "out/Movie.mkv", Media: &MediaItem{}, Year: 1999},
+			wantArg: []string{"sh", "-c", `echo "$JF_TITLE" >> downloaded.txt`, "Movie; rm -rf ~", "1999", "S00E00"},
+			wantEnv: []string{"JF_SERIES=", "JF_SEASON=", "JF_EPISODE=", "JF_TITLE=Movie; rm -rf ~", "JF_YEAR=1999"},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if got := hook.getArguments(test.job); !slices.Equal(got, test.wantArg) {
+				t.Errorf("getArguments() = %q, want %q", got, test.wantArg)
+			}
+
+			env := getEnvironment(test.job)
+			for _, variable := range test.wantEnv {
+				if !slices.Contains(env, variable) {
+					t.Errorf("environment does not contain %q", variable)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,105 @@
+package jf_requests
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"log/slog"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// Name of the client shown in the Jellyfin dashboard.
+const ClientName = "JellyfinDownloader"
+
+// Identity of this client which is sent to the server with every request.
+type ClientIdentity struct {
+	Client   string
+	Device   string
+	DeviceId string
+	Version  string
+}
+
+var clientIdentity = ClientIdentity{Client: ClientName, Device: getHostname(), DeviceId: getHostnameDeviceId(), Version: "dev"}
+
+func getHostname() string {
+	hostname, err := os.Hostname()
+	if err != nil || hostname == "" {
+		return ClientName
+	}
+
+	return hostname
+}
+
+// Derives a device id from the hostname, which is used if no device id can be stored.
+func getHostnameDeviceId() string {
+	hash := sha256.Sum256([]byte(ClientName + getHostname()))
+	return hex.EncodeToString(hash[:16])
+}
+
+// Returns the path of the file the device id is stored in.
+func GetDeviceIdPath() (string, error) {
+	configDir, err := os.UserConfigDir()
+	if err != nil {
+		return "", err
+	}
+
+	return filepath.Join(configDir, ConfigDirName, "device_id"), nil
+}
+
+// Returns the device id stored in the users config dir. A new random id is created and stored
+// on the first run, so all sessions of this installation share the same id.
+func loadDeviceId() string {
+	path, err := GetDeviceIdPath()
+	if err != nil {
+		return getHostnameDeviceId()
+	}
+
+	if content, err := os.ReadFile(path); err == nil && strings.TrimSpace(string(content)) != "" {
+		return strings.TrimSpace(string(content))
+	}
+
+	random := make([]byte, 16)
+	if _, err := rand.Read(random); err != nil {
+		return getHostnameDeviceId()
+	}
+
+	deviceId := hex.EncodeToString(random)
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err == nil {
+		err = os.WriteFile(path, []byte(deviceId+"\n"), 0600)
+	}
+
+	if err != nil {
+		slog.Debug("Failed to store the device id, falling back to an id derived from the hostname", "error", err)
+		return getHostnameDeviceId()
+	}
+
+	return deviceId
+}
+
+// Sets the device name and version which are reported to the server. If deviceName is empty,
+// the hostname is used.
+func SetClientIdentity(deviceName string, version string) {
+	if deviceName == "" {
+		deviceName = getHostname()
+	}
+
+	clientIdentity = ClientIdentity{Client: ClientName, Device: deviceName, DeviceId: loadDeviceId(), Version: version}
+}
+
+// Returns the value of the X-Emby-Authorization header, including the token if it is not empty.
+// The values are escaped, so quotes or commas in the device name do not break the header.
+func getAuthorizationHeader(token string) string {
+	header := fmt.Sprintf("MediaBrowser Client=\"%s\", Device=\"%s\", DeviceId=\"%s\", Version=\"%s\"",
+		url.PathEscape(clientIdentity.Client), url.PathEscape(clientIdentity.Device),
+		url.PathEscape(clientIdentity.DeviceId), url.PathEscape(clientIdentity.Version))
+
+	if token != "" {
+		header += fmt.Sprintf(", Token=\"%s\"", token)
+	}
+
+	return header
+}
@@ -0,0 +1,94 @@
+package jf_requests
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/fatih/color"
+)
+
+// A collection (BoxSet) with its resolved children.
+type Collection struct {
+	Name   string
+	Id     string
+	Movies []Movie
+	Series []Item
+	// Only loaded for -collection-nfo.
+	Metadata Metadata
+	// Number of children which were left out due to the limit.
+	Truncated int
+}
+
+// Fetches the children of the given collection which match the filter. Only the first limit children
+// are kept, unless limit is 0. Movies are resolved completely, so their size is known. Children which
+// cannot be resolved are returned as errors, without aborting the remaining ones.
+func GetCollectionFromItem(ctx context.Context, auth *AuthResponse, baseurl string, item *Item, filter MetadataFilter, limit int) (*Collection, []error, error) {
+	children, err := GetItemsForParentId(ctx, auth, baseurl, item)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	children = FilterItems(children, filter)
+
+	collection := Collection{Name: item.Name, Id: item.Id}
+	if limit > 0 && len(children) > limit {
+		collection.Truncated = len(children) - limit
+		children = children[:limit]
+	}
+	var childErrors []error
+
+	for _, child := range children {
+		if child.Type == "Series" {
+			collection.Series = append(collection.Series, child)
+			continue
+		}
+
+		movie, err := GetMovieFromItem(ctx, auth, baseurl, &child)
+		if err != nil {
+			childErrors = append(childErrors, fmt.Errorf("%s: %w", child.Name, err))
+			continue
+		}
+
+		collection.Movies = append(collection.Movies, *movie)
+	}
+
+	return &collection, childErrors, nil
+}
+
+// Marks the movies as members of the collection, so their nfo files name the collection as their set.
+func (collection *Collection) TagMovies() {
+	for idx := range collection.Movies {
+		collection.Movies[idx].Set = collection.Name
+	}
+}
+
+// Returns the combined size of all movies of the collection.
+func (collection *Collection) GetMoviesSize() int64 {
+	var total int64 = 0
+	for _, movie := range collection.Movies {
+		total += movie.Size
+	}
+
+	return total
+}
+
+// Prints the children of the collection which will be downloaded and asks for a confirmation.
+// Refuses if there is not enough disk space for the movies and prompts are disabled.
+func (collection *Collection) PrintAndGetConfirmation(opts DownloadOptions) bool {
+	fmt.Println("The following Collection will be downloaded:")
+	color.Green("%s (%d movies, %s)", collection.Name, len(collection.Movies), FormatBytes(collection.GetMoviesSize()))
+
+	for idx, movie := range collection.Movies {
+		color.Cyan("  └ %d. %s", idx+1, movie.Name)
+	}
+
+	for _, series := range collection.Series {
+		color.Cyan("  └ %s (Series, seasons are selected separately)", series.Name)
+	}
+
+	if !CheckDiskSpace(opts, collection.GetMoviesSize()) {
+		return false
+	}
+
+	return GetConfirmation()
+}
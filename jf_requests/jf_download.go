@@ -1,26 +1,176 @@
 package jf_requests
 
 import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
 	"errors"
 	"fmt"
 	"io"
+	"log/slog"
 	"net/http"
+	"net/url"
 	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"text/tabwriter"
 	"time"
 
+	"github.com/fatih/color"
 	"github.com/schollz/progressbar/v3"
 )
 
+// Options which control how and where files are downloaded.
+type DownloadOptions struct {
+	OutputDir   string
+	Concurrency int
+	// Directory the partial files of downloads are written to. If empty, they are written next to
+	// the outfile, which keeps the final rename atomic.
+	TempDir string
+	// What happens to files which already exist. The zero value is OverwriteChanged.
+	Overwrite OverwritePolicy
+	// How existing files are compared with the server before they are skipped.
+	SkipVerify SkipVerifyMode
+	// Download subtitles as sidecar files. If SubtitleLanguages is empty, all languages are downloaded.
+	Subtitles         bool
+	SubtitleLanguages []string
+	// Template for the filenames of episodes. If nil, the default naming scheme of the layout is used.
+	Template *FilenameTemplate
+	Layout   Layout
+	// Name the files like the original files on the server instead of by the layout or template.
+	PreserveNames bool
+	// With PreserveNames, also recreate the directories of the server below OutputDir.
+	MirrorDirs bool
+	// Order in which the episodes of a series are downloaded.
+	Sort SortOrder
+	// Only print what would be downloaded without downloading anything.
+	DryRun bool
+	// If set, a transcoded stream in the given quality is downloaded instead of the original file.
+	Quality *Quality
+	// If set, the file is remuxed into this container, e.g. mp4. Together with Quality it is the
+	// container of the transcoded stream.
+	Container string
+	// If set, original downloads are stored with this extension instead of the one derived from
+	// the container of the item.
+	Extension string
+	// Download posters, backdrops and logos.
+	Artwork bool
+	// Write Kodi style nfo files with the metadata of the items.
+	Nfo bool
+	// Write the chapters of the items into ffmetadata files.
+	Chapters bool
+	// Download the trickplay thumbnails of the items.
+	Trickplay bool
+	// Prefix the files of a playlist with their position in the playlist.
+	PlaylistIndex bool
+	// Compare the checksum of downloaded files if the server provides one.
+	Verify bool
+	// Index or language of the audio track which is kept in transcoded downloads.
+	AudioTrack string
+	// Preferred languages of audio tracks and subtitles, the most preferred first. Only used if
+	// AudioTrack or SubtitleLanguages are not set.
+	Languages []string
+	// Prefer the original audio track over the preferred languages.
+	PreferOriginalAudio bool
+	// Download the subtitles of all languages, even if preferred languages are set.
+	AllSubtitles bool
+	// If set, the status of every job is recorded in the manifest.
+	Manifest *Manifest
+	// Keep the partial files of failed downloads, so the next run can resume them.
+	KeepPartial bool
+	// Download the extras of movies and series, which are loaded by their LoadExtras.
+	Extras bool
+	// Store extras next to the movie instead of the extras directory.
+	FlattenExtras bool
+	// Executed after every downloaded episode or movie.
+	Hook *CommandHook
+	// Number of times a file whose download failed is tried again as a whole, including its
+	// verification. Unlike MaxAttempts, it also covers failures which are not transient.
+	FileRetries int
+	// Number of seasons of a series which are downloaded in parallel, each with its own progress line.
+	SeasonConcurrency int
+	// How often the partial files of running downloads are flushed to disk and their progress
+	// recorded in the manifest.
+	Checkpoint CheckpointInterval
+
+	// If set, the media file of the single selected episode or movie is written to this writer
+	// instead of a file, e.g. stdout.
+	Stream io.Writer
+
+	// Progress of the season the jobs belong to, if the seasons are downloaded in parallel.
+	seasonProgress *seasonProgress
+}
+
+// A single file which should be downloaded.
+type DownloadJob struct {
+	Name    string
+	Url     string
+	Outfile string
+	// Size in bytes as reported by the server; 0 if unknown.
+	Size int64
+	// Optional files are skipped instead of failing if they do not exist on the server.
+	Optional bool
+	// If set, the content is written to Outfile instead of downloading it from Url.
+	Content []byte
+	// Description of the audio tracks of the downloaded media, shown in the download plan.
+	AudioTracks string
+	// If set, the job fails with this error without downloading anything.
+	Err error
+	// Values the filename of an episode was built from. Stored in the manifest, so the file can be renamed later.
+	Episode *TemplateValues
+	// The item whose media file is downloaded; nil for sidecar files like subtitles or artwork.
+	Media *MediaItem
+	// Production year of a movie; 0 if it is unknown. The year of an episode is part of Episode.
+	Year int
+}
+
+// The outcome of a single DownloadJob.
+type DownloadResult struct {
+	Job     DownloadJob
+	Err     error
+	Skipped bool
+	// Number of bytes which were transferred from the server, including failed attempts.
+	Bytes int64
+}
+
+// Returns the outcome of the download as recorded in the manifest. A result with an error counts as
+// failed even if it is skipped as well, so all summaries and the manifest agree.
+func (result DownloadResult) Status() ManifestStatus {
+	if result.Err != nil {
+		return ManifestFailed
+	} else if result.Skipped {
+		return ManifestSkipped
+	}
+
+	return ManifestDone
+}
+
+// Checks if the output file of the given job already exists with the size reported by the server.
+func (job *DownloadJob) IsAlreadyDownloaded() bool {
+	info, err := os.Stat(job.Outfile)
+	if err != nil || job.Size <= 0 {
+		return false
+	}
+
+	return info.Size() == job.Size
+}
+
+// Guards terminal output while multiple downloads are running at the same time.
+var outputMutex sync.Mutex
+
 func CreatePBar(length int64, description string) *progressbar.ProgressBar {
-	desc := ""
 	return progressbar.NewOptions64(
 		length,
-		progressbar.OptionSetDescription(desc),
+		progressbar.OptionSetDescription(description),
 		progressbar.OptionSetWriter(os.Stderr),
 		progressbar.OptionShowBytes(true),
 		progressbar.OptionSetWidth(10),
 		progressbar.OptionThrottle(1*time.Second),
 		progressbar.OptionShowCount(),
+		progressbar.OptionSetPredictTime(true),
 		progressbar.OptionOnCompletion(func() {
 			fmt.Fprint(os.Stderr, "\n")
 		}),
@@ -31,29 +181,602 @@ func CreatePBar(length int64, description string) *progressbar.ProgressBar {
 	)
 }
 
-func DownloadFromUrl(downloadLink string, name string, outfile string, max int, current int) error {
-	req, _ := http.NewRequest("GET", downloadLink, nil)
-	resp, err := http.DefaultClient.Do(req)
+// Suffix of files which are not completely downloaded yet.
+const PartialFileSuffix = ".part"
+
+// Returns the path of the partial file of the given outfile. Inside tempDir, the name is prefixed
+// with a hash of the path of the outfile, so files of the same name in different directories do
+// not collide and the next run finds the partial file again to resume it.
+func GetPartialFile(outfile string, tempDir string) string {
+	if tempDir == "" {
+		return outfile + PartialFileSuffix
+	}
 
+	path, err := filepath.Abs(outfile)
 	if err != nil {
-		return errors.New(fmt.Sprintf("Request Failed: %s", err))
+		path = outfile
+	}
+
+	hash := sha256.Sum256([]byte(path))
+	return filepath.Join(tempDir, fmt.Sprintf("%s-%s%s", hex.EncodeToString(hash[:6]), filepath.Base(outfile), PartialFileSuffix))
+}
+
+// Downloads the file of the given job. progressName is shown in the progress bar. If showProgress
+// is false, no progress bar is rendered, which is required when multiple downloads are running in parallel.
+//
+// The data is written into a partial file first, which is moved to the outfile of the job once the
+// transfer is complete and verified. The partial file is stored in opts.TempDir if it is set. If a
+// partial file of a previous run exists, the download is resumed where it stopped, or started over
+// once if the server rejects its range. When the context is cancelled, the partial file is kept for resuming.
+// Returns the number of bytes which were transferred, which is also set if the download failed.
+func DownloadFromUrl(ctx context.Context, job DownloadJob, progressName string, showProgress bool, opts DownloadOptions) (int64, error) {
+	name, outfile := job.Name, job.Outfile
+	partfile := GetPartialFile(outfile, opts.TempDir)
+
+	var offset int64 = 0
+	if info, err := os.Stat(partfile); err == nil {
+		offset = info.Size()
+	}
+
+	// After a crash, the data behind the last checkpoint may not have reached the disk. It is
+	// downloaded again rather than trusted.
+	if opts.Manifest != nil {
+		if checkpoint, ok := opts.Manifest.GetCheckpoint(outfile); ok && checkpoint < offset {
+			slog.Debug(fmt.Sprintf("Continuing %s at its last checkpoint", name), "checkpoint", checkpoint, "size", offset)
+			if err := os.Truncate(partfile, checkpoint); err == nil {
+				offset = checkpoint
+			}
+		}
+	}
+
+	resp, offset, err := requestDownload(ctx, job, partfile, offset)
+	if err != nil {
+		return 0, err
+	} else if resp == nil {
+		// The partial file of an earlier run already contains the whole file
+		return 0, finishDownload(job, partfile, http.Header{}, false, opts)
 	}
 
 	defer resp.Body.Close()
 
-	f, err := os.OpenFile(outfile, os.O_CREATE|os.O_WRONLY, 0644)
+	flags := os.O_CREATE | os.O_WRONLY
+	var expectedSize int64 = -1
+
+	switch resp.StatusCode {
+	case http.StatusPartialContent:
+		slog.Debug(fmt.Sprintf("Resuming download of %s at byte %d", name, offset))
+		flags |= os.O_APPEND
+		expectedSize = GetTotalSizeFromContentRange(resp.Header.Get("Content-Range"))
+	case http.StatusOK:
+		// The server ignored the range request, therefore we need to start over.
+		offset = 0
+		flags |= os.O_TRUNC
+		if resp.ContentLength >= 0 {
+			expectedSize = resp.ContentLength
+		}
+	default:
+		// Read the error page, so the connection can be reused for the next request
+		io.Copy(io.Discard, io.LimitReader(resp.Body, 64*1024))
+		return 0, newStatusError(resp, "")
+	}
+
+	// Transcoded streams are usually sent without a Content-Length. The size reported by the server
+	// is used for the progress instead, if there is one; otherwise the progress is indeterminate.
+	progressTotal := resp.ContentLength
+	if progressTotal < 0 && expectedSize >= 0 {
+		progressTotal = expectedSize - offset
+	} else if progressTotal < 0 && job.Size > 0 {
+		progressTotal = job.Size - offset
+	}
+
+	if expectedSize < 0 {
+		slog.Debug(fmt.Sprintf("The server did not report the length of %s", name), "size", job.Size)
+	}
+
+	if err := os.MkdirAll(filepath.Dir(partfile), 0755); err != nil {
+		return 0, errors.New(fmt.Sprintf("Failed to create directory: %s", err))
+	}
+
+	f, err := os.OpenFile(partfile, flags, 0644)
 	if err != nil {
-		return errors.New(fmt.Sprintf("Failed to open file: %s", err))
+		return 0, errors.New(fmt.Sprintf("Failed to open file: %s", err))
 	}
 
 	defer f.Close()
 
-	bar := CreatePBar(resp.ContentLength, fmt.Sprintf("downloading %d/%d", current, max))
-	io.Copy(io.MultiWriter(f, bar), resp.Body)
+	var reader io.Reader = NewRateLimitedReader(ctx, resp.Body)
+	if opts.seasonProgress != nil && job.Episode != nil {
+		reader = &seasonProgressReader{reader: reader, progress: opts.seasonProgress}
+	}
+
+	if showProgress || JsonOutputEnabled() {
+		reader = NewProgressReader(reader, progressName, progressTotal)
+	}
+
+	var writer io.Writer = f
+	var checkpoints *checkpointWriter
+	if opts.Checkpoint.Enabled() {
+		// A checkpoint of an earlier partial file must not be applied to the new one
+		if offset == 0 && opts.Manifest != nil {
+			opts.Manifest.SetCheckpoint(outfile, 0)
+		}
+
+		checkpoints = newCheckpointWriter(f, offset, opts.Checkpoint, func(checkpoint int64) {
+			slog.Debug(fmt.Sprintf("Flushed %s to disk", name), "checkpoint", checkpoint)
+			if opts.Manifest != nil {
+				if err := opts.Manifest.SetCheckpoint(outfile, checkpoint); err != nil {
+					slog.Debug(fmt.Sprintf("Failed to record the checkpoint of %s", name), "error", err)
+				}
+			}
+		})
+		writer = checkpoints
+	}
+
+	written, err := io.Copy(writer, reader)
+
+	// What was received before an interruption is kept for the next attempt
+	if err != nil && checkpoints != nil {
+		checkpoints.checkpoint()
+	}
+
+	if err != nil && ctx.Err() != nil {
+		return written, fmt.Errorf("Download of %s cancelled: %w", name, ctx.Err())
+	} else if err != nil {
+		return written, &ConnectionError{Err: errors.New(fmt.Sprintf("Download of %s interrupted: %s", name, err))}
+	}
+
+	if expectedSize >= 0 && offset+written != expectedSize {
+		return written, &ConnectionError{Err: errors.New(fmt.Sprintf("Download of %s incomplete: got %d of %d bytes", name, offset+written, expectedSize))}
+	} else if expectedSize < 0 && offset+written == 0 {
+		// Without a length, a stream which was read to its end without an error counts as complete,
+		// as long as it contained anything at all
+		return written, &ConnectionError{Err: errors.New(fmt.Sprintf("Download of %s returned no data", name))}
+	}
+
+	if checkpoints != nil {
+		if err := checkpoints.checkpoint(); err != nil {
+			return written, err
+		}
+	}
+
+	// Data which was not flushed to disk yet must not end up in the final file
+	if err := f.Close(); err != nil {
+		return written, errors.New(fmt.Sprintf("Failed to write %s: %s", partfile, err))
+	}
+
+	// The checksum in the header of a partial response only covers the requested range
+	return written, finishDownload(job, partfile, resp.Header, opts.Verify && offset == 0, opts)
+}
+
+// Requests the file of the job, continuing at the given offset of the partial file. Returns the
+// response and the offset it starts at. If the server rejects the range, the partial file is either
+// complete already, which is reported by a nil response, or it does not match the file on the
+// server anymore and the download starts over once.
+func requestDownload(ctx context.Context, job DownloadJob, partfile string, offset int64) (*http.Response, int64, error) {
+	for restarted := false; ; restarted = true {
+		req, _ := http.NewRequestWithContext(ctx, "GET", job.Url, nil)
+		if offset > 0 {
+			req.Header.Set("Range", fmt.Sprintf("bytes=%d-", offset))
+		}
+
+		start := time.Now()
+		resp, err := httpClient.Do(req)
+		if err != nil {
+			err = redactError(err)
+			slog.Debug(fmt.Sprintf("Download request against %s failed", RedactUrl(job.Url)), "duration", time.Since(start), "error", err)
+			return nil, offset, &ConnectionError{Err: err}
+		}
+
+		slog.Debug(fmt.Sprintf("Response from %s", RedactUrl(job.Url)), "status", resp.StatusCode, "offset", offset, "duration", time.Since(start))
+		if resp.StatusCode != http.StatusRequestedRangeNotSatisfiable {
+			return resp, offset, nil
+		}
+
+		// The response is closed first, so it does not hold on to a connection slot of the server
+		resp.Body.Close()
+		if offset > 0 && GetTotalSizeFromContentRange(resp.Header.Get("Content-Range")) == offset {
+			slog.Debug(fmt.Sprintf("The partial file of %s is already complete", job.Name), "size", offset)
+			return nil, offset, nil
+		} else if restarted || offset == 0 {
+			return nil, offset, newStatusError(resp, "")
+		}
+
+		// The partial file does not match the file on the server anymore. Start from scratch.
+		if err := os.Remove(partfile); err != nil && !errors.Is(err, os.ErrNotExist) {
+			return nil, offset, errors.New(fmt.Sprintf("Failed to remove %s to start over: %s", partfile, err))
+		}
+
+		offset = 0
+	}
+}
+
+// Verifies the completely downloaded partial file of the job and moves it to the outfile. The
+// checksum in the given header is only compared if verifyChecksum is set.
+func finishDownload(job DownloadJob, partfile string, header http.Header, verifyChecksum bool, opts DownloadOptions) error {
+	outfile := job.Outfile
+	if err := VerifyDownload(partfile, job.Size, header, verifyChecksum); err != nil {
+		if corruptPath := MarkCorrupt(partfile); corruptPath != "" {
+			return errors.New(fmt.Sprintf("%s (kept as %s)", err, corruptPath))
+		}
+
+		return err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(outfile), 0755); err != nil {
+		return errors.New(fmt.Sprintf("Failed to create directory: %s", err))
+	}
+
+	if err := MoveFile(partfile, outfile); err != nil {
+		return errors.New(fmt.Sprintf("Failed to move %s to %s: %s", partfile, outfile, err))
+	}
+
+	// Remember the ETag and the verified checksum, so the next run with -skip-verify hash can
+	// compare the file without computing its checksum
+	if opts.Manifest != nil {
+		hash := FileHash{ETag: header.Get("ETag")}
+		if expected := getExpectedChecksum(header); verifyChecksum && expected != nil {
+			hash.Algorithm, hash.Checksum = expected.Algorithm, hex.EncodeToString(expected.Sum)
+		}
+
+		if err := opts.Manifest.SetHash(outfile, hash); err != nil {
+			slog.Debug(fmt.Sprintf("Failed to store the hash of %s", outfile), "error", err)
+		}
+	}
+
+	return nil
+}
+
+// Writes the given content to outfile, creating missing directories.
+func WriteLocalFile(outfile string, content []byte) error {
+	if err := os.MkdirAll(filepath.Dir(outfile), 0755); err != nil {
+		return errors.New(fmt.Sprintf("Failed to create directory: %s", err))
+	}
+
+	// Like downloads, the content is written under a temporary name first, so an interrupted write
+	// does not leave a truncated file which is taken for a finished one
+	tmpfile := outfile + PartialFileSuffix
+	if err := os.WriteFile(tmpfile, content, 0644); err != nil {
+		os.Remove(tmpfile)
+		return errors.New(fmt.Sprintf("Failed to write %s: %s", outfile, err))
+	}
+
+	if err := os.Rename(tmpfile, outfile); err != nil {
+		os.Remove(tmpfile)
+		return errors.New(fmt.Sprintf("Failed to move %s to %s: %s", tmpfile, outfile, err))
+	}
 
 	return nil
 }
 
+// Extracts the total size from a Content-Range header like "bytes 100-199/200".
+// Returns -1 if the total size is unknown.
+func GetTotalSizeFromContentRange(contentRange string) int64 {
+	idx := strings.LastIndex(contentRange, "/")
+	if idx < 0 {
+		return -1
+	}
+
+	total, err := strconv.ParseInt(contentRange[idx+1:], 10, 64)
+	if err != nil {
+		return -1
+	}
+
+	return total
+}
+
+// Downloads all given jobs using up to opts.Concurrency parallel downloads. Failing jobs do not abort
+// the remaining ones; the outcome of every job is returned in the same order as the given jobs.
+// Once the context is cancelled, running downloads are stopped and no further jobs are started.
+func DownloadJobs(ctx context.Context, jobs []DownloadJob, opts DownloadOptions) []DownloadResult {
+	if opts.DryRun {
+		if JsonOutputEnabled() {
+			for _, job := range jobs {
+				event := map[string]any{"name": job.Name, "path": job.Outfile, "size": job.Size, "audio": job.AudioTracks}
+				if job.Err != nil {
+					event["error"] = job.Err.Error()
+				}
+
+				EmitEvent("planned", event)
+			}
+		} else {
+			PrintDownloadPlan(jobs)
+		}
+
+		return nil
+	}
+
+	if opts.Stream != nil {
+		return streamJobs(ctx, jobs, opts)
+	}
+
+	// The progress of parallel downloads is only shown per season or not at all
+	concurrency := max(opts.Concurrency, 1)
+	showProgress := concurrency == 1 && opts.seasonProgress == nil
+
+	// Make sure all files can be written on the current platform
+	jobs = slices.Clone(jobs)
+	for idx := range jobs {
+		jobs[idx].Outfile = GetFilesystemPath(jobs[idx].Outfile)
+	}
+
+	if opts.Manifest != nil {
+		if err := opts.Manifest.Add(jobs); err != nil {
+			slog.Warn(err.Error())
+		}
+	}
+
+	results := make([]DownloadResult, len(jobs))
+	setResult := func(idx int, result DownloadResult) {
+		results[idx] = result
+		if opts.seasonProgress != nil {
+			opts.seasonProgress.addResult(result)
+		}
+
+		if opts.Manifest != nil {
+			if err := opts.Manifest.Update(result); err != nil {
+				slog.Warn(err.Error())
+			}
+		}
+	}
+
+	semaphore := make(chan struct{}, concurrency)
+	var wg sync.WaitGroup
+
+	for idx, job := range jobs {
+		if ctx.Err() == nil {
+			select {
+			case semaphore <- struct{}{}:
+			case <-ctx.Done():
+			}
+		}
+
+		if ctx.Err() != nil {
+			setResult(idx, DownloadResult{Job: job, Err: ctx.Err()})
+			continue
+		}
+
+		// Without a valid session all remaining downloads would be rejected as well. A failed
+		// command which should stop the batch ends the remaining downloads the same way.
+		err := SessionExpired()
+		if err == nil {
+			err = opts.Hook.Failed()
+		}
+
+		if err != nil {
+			<-semaphore
+			setResult(idx, DownloadResult{Job: job, Err: err})
+			continue
+		}
+
+		wg.Add(1)
+
+		go func(idx int, job DownloadJob) {
+			defer wg.Done()
+			defer func() { <-semaphore }()
+
+			skip := opts.Overwrite.skipReason(&job)
+			// Files without a known size, like transcoded streams, have no checksum on the server to compare with
+			if skip.reason != "" && opts.SkipVerify == SkipVerifyHash && opts.Overwrite != OverwriteNever && job.Size > 0 {
+				skip = verifyExistingFile(ctx, &job, opts.Manifest)
+			}
+
+			if skip.reason != "" {
+				printMessage(func() {
+					color.Yellow("Skipping %s: %s %s", job.Name, job.Outfile, skip.message)
+				})
+
+				EmitEvent("skipped", map[string]any{"name": job.Name, "path": job.Outfile, "reason": skip.reason, "message": skip.message})
+				setResult(idx, DownloadResult{Job: job, Skipped: true})
+				return
+			}
+
+			// The progress view of the seasons replaces the lines of the single files
+			if opts.seasonProgress == nil {
+				printMessage(func() {
+					color.Cyan("Downloading %d/%d: %s", idx+1, len(jobs), job.Name)
+				})
+			}
+
+			EmitEvent("start", map[string]any{"name": job.Name, "path": job.Outfile, "size": job.Size})
+
+			// Progress events need to be assignable to the job they belong to
+			progressName := fmt.Sprintf("downloading %d/%d", idx+1, len(jobs))
+			if JsonOutputEnabled() {
+				progressName = job.Name
+			}
+
+			var err error
+			var transferred int64
+			if job.Err != nil {
+				err = job.Err
+			} else if job.Content != nil {
+				err = WriteLocalFile(job.Outfile, job.Content)
+			} else {
+				// Besides the retries of single requests, the whole file is tried again, e.g. after a
+				// failed verification. The partial file is kept, so interrupted downloads continue.
+				for retry := 0; ; retry++ {
+					// Expired tokens are refreshed once and the link is updated accordingly
+					_, err = withReauthentication(ctx, getLinkToken(job.Url), func(token string) (any, error) {
+						if token != getLinkToken(job.Url) {
+							job.Url = addToken(job.Url, token)
+						}
+
+						return WithRetry(ctx, fmt.Sprintf("Download of %s", job.Name), func() (any, error) {
+							written, err := DownloadFromUrl(ctx, job, progressName, showProgress, opts)
+							transferred += written
+							return nil, err
+						})
+					})
+
+					if err == nil || retry >= opts.FileRetries || !IsFileRetriable(err) {
+						break
+					}
+
+					delay := getRetryDelayFor(err, retry+1)
+					slog.Warn(fmt.Sprintf("Download of %s failed, trying the file again in %s", job.Name, delay.Round(time.Millisecond)), "retry", retry+1, "retries", opts.FileRetries, "error", err)
+					select {
+					case <-ctx.Done():
+					case <-time.After(delay):
+					}
+
+					if ctx.Err() != nil {
+						err = ctx.Err()
+						break
+					}
+				}
+			}
+			// Partial files of interrupted downloads are kept, so the next run resumes them
+			if err != nil && job.Content == nil && ctx.Err() == nil && !opts.KeepPartial {
+				os.Remove(GetPartialFile(job.Outfile, opts.TempDir))
+			}
+
+			var statusErr *StatusError
+			if job.Optional && errors.As(err, &statusErr) && statusErr.StatusCode == http.StatusNotFound {
+				slog.Debug(fmt.Sprintf("Skipping %s: not available on the server", job.Name))
+				EmitEvent("skipped", map[string]any{"name": job.Name, "path": job.Outfile, "reason": "unavailable"})
+				setResult(idx, DownloadResult{Job: job, Skipped: true})
+				return
+			}
+
+			// A failing command only fails the job if the remaining downloads are stopped as well
+			if err == nil && opts.Hook.appliesTo(job) {
+				if hookErr := opts.Hook.Run(ctx, job); hookErr != nil {
+					printMessage(func() {
+						color.Red(hookErr.Error())
+					})
+
+					if opts.Hook.Failed() != nil {
+						err = hookErr
+					}
+				}
+			}
+
+			setResult(idx, DownloadResult{Job: job, Err: err, Bytes: transferred})
+			if err != nil {
+				EmitEvent("error", map[string]any{"name": job.Name, "path": job.Outfile, "error": err.Error()})
+			} else {
+				EmitEvent("complete", map[string]any{"name": job.Name, "path": job.Outfile})
+			}
+
+			if !showProgress {
+				printMessage(func() {
+					if err != nil {
+						color.Red("Failed %s: %s", job.Name, err)
+					} else if opts.seasonProgress == nil {
+						color.Green("Finished %s", job.Name)
+					}
+				})
+			}
+		}(idx, job)
+	}
+
+	wg.Wait()
+	return results
+}
+
+// Prints a table of all files which would be downloaded, including their size and the total size.
+func PrintDownloadPlan(jobs []DownloadJob) {
+	writer := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(writer, "NAME\tPATH\tSIZE\tAUDIO")
+
+	var total int64 = 0
+	unknown := 0
+	for _, job := range jobs {
+		size := "unknown"
+		if job.Size > 0 {
+			size = FormatBytes(job.Size)
+			total += job.Size
+		} else {
+			unknown += 1
+		}
+
+		audio := job.AudioTracks
+		if job.Err != nil {
+			audio = fmt.Sprintf("error: %s", job.Err)
+		}
+
+		fmt.Fprintf(writer, "%s\t%s\t%s\t%s\n", job.Name, job.Outfile, size, audio)
+	}
+
+	writer.Flush()
+
+	if unknown > 0 {
+		color.Green("Total: %d files, %s (size of %d files unknown)", len(jobs), FormatBytes(total), unknown)
+	} else {
+		color.Green("Total: %d files, %s", len(jobs), FormatBytes(total))
+	}
+}
+
+// Prints which downloads succeeded, which were skipped and which failed. Returns true if no download failed.
+func PrintDownloadSummary(results []DownloadResult) bool {
+	downloaded, skipped, failed := 0, 0, 0
+	for _, result := range results {
+		switch result.Status() {
+		case ManifestFailed:
+			failed += 1
+		case ManifestSkipped:
+			skipped += 1
+		default:
+			downloaded += 1
+		}
+	}
+
+	EmitEvent("summary", map[string]any{"total": len(results), "downloaded": downloaded, "skipped": skipped, "failed": failed})
+
+	fmt.Printf("Downloaded %d, skipped %d and failed %d of %d files:\n", downloaded, skipped, failed, len(results))
+	for _, result := range results {
+		switch result.Status() {
+		case ManifestFailed:
+			color.Red("  ✗ %s: %s", result.Job.Name, result.Err)
+		case ManifestSkipped:
+			color.Yellow("  - %s (skipped)", result.Job.Name)
+		default:
+			color.Green("  ✓ %s", result.Job.Name)
+		}
+	}
+
+	return failed == 0
+}
+
 func GetDownloadLinkForId(baseUrl string, token string, id string) string {
-	return fmt.Sprintf(baseUrl+"/Items/%s/Download?api_key=%s", id, token)
+	return BuildUrl(baseUrl, fmt.Sprintf("Items/%s/Download", id), url.Values{"api_key": {token}})
+}
+
+// Makes sure the given output directory exists and is writable. Missing directories are created.
+// An empty path refers to the current working directory.
+func PrepareOutputDir(outputDir string) error {
+	if outputDir == "" {
+		outputDir = "."
+	}
+
+	return prepareDir(outputDir, "output directory")
+}
+
+// Makes sure the directory for the partial files given by -tmpdir exists and is writable.
+func PrepareTempDir(tempDir string) error {
+	return prepareDir(tempDir, "temporary directory")
+}
+
+// Creates the directory if it is missing and checks if it is writable. kind names the directory
+// in the errors.
+func prepareDir(dir string, kind string) error {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return errors.New(fmt.Sprintf("Failed to create %s: %s", kind, err))
+	}
+
+	// Check if we are allowed to write into the directory by creating a temporary file.
+	f, err := os.CreateTemp(dir, ".jfdl-*")
+	if err != nil {
+		return errors.New(fmt.Sprintf("The %s is not writable: %s", kind, err))
+	}
+
+	f.Close()
+	os.Remove(f.Name())
+
+	return nil
+}
+
+// Returns the path of the given filename inside the output directory.
+func GetOutputPath(outputDir string, filename string) string {
+	return filepath.Join(outputDir, filename)
 }
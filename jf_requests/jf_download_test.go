@@ -0,0 +1,115 @@
+package jf_requests
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// Starts a server which serves content with support for range requests and counts the requests.
+func newRangeServer(t *testing.T, content []byte) (*httptest.Server, *atomic.Int32) {
+	t.Helper()
+	var requests atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests.Add(1)
+		http.ServeContent(w, r, "file", time.Time{}, bytes.NewReader(content))
+	}))
+	t.Cleanup(server.Close)
+	return server, &requests
+}
+
+func newDownloadJob(t *testing.T, url string, size int64) DownloadJob {
+	t.Helper()
+	return DownloadJob{Name: "file", Url: url, Outfile: filepath.Join(t.TempDir(), "file.mkv"), Size: size}
+}
+
+func assertDownloaded(t *testing.T, job DownloadJob, content []byte) {
+	t.Helper()
+	data, err := os.ReadFile(job.Outfile)
+	if err != nil {
+		t.Fatalf("outfile was not written: %s", err)
+	}
+
+	if !bytes.Equal(data, content) {
+		t.Fatalf("outfile contains %q, want %q", data, content)
+	}
+
+	if _, err := os.Stat(GetPartialFile(job.Outfile, "")); !os.IsNotExist(err) {
+		t.Fatalf("partial file still exists: %v", err)
+	}
+}
+
+func TestDownloadFromUrlFinishesCompletePartialFile(t *testing.T) {
+	content := []byte("0123456789")
+	server, requests := newRangeServer(t, content)
+	job := newDownloadJob(t, server.URL, int64(len(content)))
+	if err := os.WriteFile(GetPartialFile(job.Outfile, ""), content, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	written, err := DownloadFromUrl(context.Background(), job, job.Name, false, DownloadOptions{})
+	if err != nil {
+		t.Fatalf("DownloadFromUrl failed: %s", err)
+	}
+
+	if written != 0 {
+		t.Errorf("transferred %d bytes, want 0", written)
+	}
+
+	if n := requests.Load(); n != 1 {
+		t.Errorf("sent %d requests, want 1", n)
+	}
+
+	assertDownloaded(t, job, content)
+}
+
+func TestDownloadFromUrlRestartsMismatchedPartialFile(t *testing.T) {
+	content := []byte("0123456789")
+	server, requests := newRangeServer(t, content)
+	job := newDownloadJob(t, server.URL, int64(len(content)))
+	if err := os.WriteFile(GetPartialFile(job.Outfile, ""), []byte("a partial file which is too long"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := DownloadFromUrl(context.Background(), job, job.Name, false, DownloadOptions{}); err != nil {
+		t.Fatalf("DownloadFromUrl failed: %s", err)
+	}
+
+	if n := requests.Load(); n != 2 {
+		t.Errorf("sent %d requests, want 2", n)
+	}
+
+	assertDownloaded(t, job, content)
+}
+
+func TestDownloadFromUrlRestartsOnlyOnce(t *testing.T) {
+	var requests atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests.Add(1)
+		w.WriteHeader(http.StatusRequestedRangeNotSatisfiable)
+	}))
+	t.Cleanup(server.Close)
+
+	job := newDownloadJob(t, server.URL, 10)
+	if err := os.WriteFile(GetPartialFile(job.Outfile, ""), []byte("01234"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := DownloadFromUrl(context.Background(), job, job.Name, false, DownloadOptions{}); err == nil {
+		t.Fatal("DownloadFromUrl succeeded, want an error")
+	}
+
+	if n := requests.Load(); n != 2 {
+		t.Errorf("sent %d requests, want 2", n)
+	}
+
+	if _, err := os.Stat(job.Outfile); !os.IsNotExist(err) {
+		t.Errorf("outfile exists: %v", err)
+	}
+}
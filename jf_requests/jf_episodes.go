@@ -1,35 +1,95 @@
 package jf_requests
 
 import (
+	"context"
 	"errors"
 	"fmt"
+	"log/slog"
+	"net/url"
+	"path/filepath"
+	"sort"
 	"strings"
+	"sync"
 
 	"github.com/fatih/color"
 )
 
 type Episode struct {
-	Name      string
-	Id        string
-	Container string
+	MediaItem
+	// Episode number within the season
+	Index int
 }
 
 type Season struct {
 	Id       string
 	Name     string
+	Index    int
 	Episodes []Episode
+	// Episodes which are known from the metadata but have no media file on the server.
+	Missing []Episode
 }
 
 type Series struct {
-	Name    string
-	Id      string
-	Seasons []Season
+	Name     string
+	Id       string
+	Year     int
+	Seasons  []Season
+	Metadata Metadata
+	// Only loaded by LoadExtras.
+	Extras []Extra
+}
+
+// Maximum number of seasons whose episodes are fetched in parallel.
+const seasonFetchConcurrency = 4
+
+// Fields which are requested for every episode and movie.
+const mediaItemFields = "MediaSources,Overview,Genres,DateCreated,Chapters,Trickplay"
+
+// Fetches the episodes of the given season, sorted by their episode number.
+// The user data, e.g. whether an episode was watched, is returned for the given user.
+func getSeasonEpisodes(ctx context.Context, auth *AuthResponse, baseurl string, seriesId string, season *Season) error {
+	params := url.Values{"SeasonId": {season.Id}, "UserId": {auth.UserId}, "Fields": {mediaItemFields}}
+	requestUrl := BuildUrl(baseurl, fmt.Sprintf("Shows/%s/Episodes", seriesId), params)
+
+	res, err := MakeRequest(ctx, auth.Token, requestUrl, "GET", nil)
+	if err != nil {
+		return errors.New(fmt.Sprintf("Failed to obtain the episodes of %s: %s", season.Name, err))
+	}
+
+	items, _ := res["Items"].([]any)
+	for _, rawItem := range items {
+		rawEpisode := rawItem.(map[string]any)
+		episode := Episode{
+			MediaItem: GetMediaItemFromRawItem(rawEpisode),
+			Index:     GetIntFromRawItem(rawEpisode, "IndexNumber", len(season.Episodes)+len(season.Missing)+1),
+		}
+
+		// Episodes without a media source can not be downloaded, they only exist in the metadata
+		if len(episode.MediaSources) == 0 {
+			slog.Debug(fmt.Sprintf("Episode %s of %s has no media file on the server", episode.Name, season.Name))
+			season.Missing = append(season.Missing, episode)
+		} else {
+			season.Episodes = append(season.Episodes, episode)
+		}
+	}
+
+	for _, episodes := range [][]Episode{season.Episodes, season.Missing} {
+		sort.SliceStable(episodes, func(i, j int) bool {
+			return episodes[i].Index < episodes[j].Index
+		})
+	}
+
+	return nil
 }
 
-func GetSeriesFromItem(token string, baseurl string, item *Item) (*Series, error) {
-	requestUrl := fmt.Sprintf("%s/Shows/%s/Episodes", baseurl, item.Id)
+// Fetches the seasons of the series and their episodes. The episodes of up to seasonFetchConcurrency
+// seasons are fetched in parallel. If the episodes of any season can not be fetched, an error is
+// returned instead of a series with missing episodes. The episodes without a media file are only
+// kept if includeMissing is set, together with the seasons which consist of nothing else.
+func GetSeriesFromItem(ctx context.Context, auth *AuthResponse, baseurl string, item *Item, includeMissing bool) (*Series, error) {
+	requestUrl := BuildUrl(baseurl, fmt.Sprintf("Shows/%s/Seasons", item.Id), nil)
 
-	res, err := MakeRequest(token, requestUrl, "GET", nil)
+	res, err := MakeRequest(ctx, auth.Token, requestUrl, "GET", nil)
 	if err != nil {
 		return nil, err
 	}
@@ -37,53 +97,57 @@ func GetSeriesFromItem(token string, baseurl string, item *Item) (*Series, error
 	var result Series = Series{
 		Id:   item.Id,
 		Name: item.Name,
+		Year: item.Year,
 	}
 
-	items := res["Items"].([]any)
-
-	var seasons []Season
-	var currentSeason *Season
-	var seasonId string
-	var lastSeasonId string
-	for index := 0; index < len(items); index += 1 {
-		if index+1 < len(items) {
-			seasonId = items[index+1].(map[string]any)["SeasonId"].(string)
+	items, _ := res["Items"].([]any)
+	seasons := make([]Season, len(items))
+	for idx, rawItem := range items {
+		rawSeason := rawItem.(map[string]any)
+		seasons[idx] = Season{
+			Id:    GetStringFromRawItem(rawSeason, "Id"),
+			Name:  GetStringFromRawItem(rawSeason, "Name"),
+			Index: GetIntFromRawItem(rawSeason, "IndexNumber", idx+1),
 		}
+	}
 
-		// Create Initial Season
-		if index == 0 {
-			currentSeason = &Season{
-				Id:   seasonId,
-				Name: items[index].(map[string]any)["SeasonName"].(string),
-			}
+	// Every worker only writes the season and the error at its own index
+	seasonErrors := make([]error, len(seasons))
+	semaphore := make(chan struct{}, seasonFetchConcurrency)
+	var wg sync.WaitGroup
 
-			lastSeasonId = seasonId
-		}
+	for idx := range seasons {
+		wg.Add(1)
+		go func(idx int) {
+			defer wg.Done()
+			semaphore <- struct{}{}
+			defer func() { <-semaphore }()
 
-		ep := Episode{
-			Name:      items[index].(map[string]any)["Name"].(string),
-			Id:        items[index].(map[string]any)["Id"].(string),
-			Container: items[index].(map[string]any)["Container"].(string)}
+			seasonErrors[idx] = getSeasonEpisodes(ctx, auth, baseurl, item.Id, &seasons[idx])
+		}(idx)
+	}
 
-		currentSeason.Episodes = append(currentSeason.Episodes, ep)
+	wg.Wait()
 
-		if seasonId != lastSeasonId {
-			seasons = append(seasons, *currentSeason)
-			currentSeason = &Season{
-				Id:   seasonId,
-				Name: items[index+1].(map[string]any)["SeasonName"].(string),
-			}
+	if err := errors.Join(seasonErrors...); err != nil {
+		return nil, err
+	}
 
-			lastSeasonId = seasonId
+	sort.SliceStable(seasons, func(i, j int) bool {
+		return seasons[i].Index < seasons[j].Index
+	})
+
+	// Seasons without episodes can not be downloaded, but their missing episodes can be reported
+	for _, season := range seasons {
+		if !includeMissing {
+			season.Missing = nil
 		}
 
-		if index+1 == len(items) {
-			seasons = append(seasons, *currentSeason)
-			break
+		if len(season.Episodes) > 0 || len(season.Missing) > 0 {
+			result.Seasons = append(result.Seasons, season)
 		}
 	}
-    seasons = append(seasons, currentSeason)
-	result.Seasons = seasons
+
 	return &result, nil
 }
 
@@ -94,20 +158,39 @@ func (series *Series) GetSeasonForId(seasonId string) (*Season, error) {
 		}
 	}
 
-	return nil, errors.New(fmt.Sprint("No Season found for id %s", seasonId))
+	return nil, errors.New(fmt.Sprintf("No Season found for id %s", seasonId))
+}
+
+// Returns the episodes of the given seasons which have no media file on the server, e.g.
+// "The Office S02E03 The Client".
+func (series *Series) GetMissingEpisodes(seasons []Season) []string {
+	var missing []string
+	for _, season := range seasons {
+		for _, episode := range season.Missing {
+			missing = append(missing, fmt.Sprintf("%s S%02dE%02d %s", series.Name, season.Index, episode.Index, episode.Name))
+		}
+	}
+
+	return missing
 }
 
 func (series *Series) PrintAndGetSelection() ([]Season, error) {
-	fmt.Println("Which Seasons do you want to download:")
+	printMenu := func() {
+		fmt.Println("Which Seasons do you want to download:")
 
-	color.Cyan("  0. All")
-	for idx, season := range series.Seasons {
-		color.Cyan("  %d. %s", idx+1, season.Name)
+		color.Cyan("  0. All")
+		for idx, season := range series.Seasons {
+			color.Cyan("  %d. %s", idx+1, season.Name)
+		}
 	}
 
-	choice, err := GetUserChoice(len(series.Seasons))
-	if err != nil {
-		return nil, errors.New("Only provide a single number")
+	// 0 selects all seasons
+	printMenu()
+	choice, err := GetUserChoice(0, len(series.Seasons), printMenu)
+	if errors.Is(err, ErrPromptDisabled) {
+		return nil, errors.New("Cannot select seasons interactively when -yes is set. Pass -all or -seasonid instead.")
+	} else if err != nil {
+		return nil, err
 	}
 
 	if choice == 0 {
@@ -118,7 +201,51 @@ func (series *Series) PrintAndGetSelection() ([]Season, error) {
 
 }
 
-func (series *Series) PrintAndGetConfirmation(seasonsToDownload []Season) bool {
+// Prints all episodes of the given seasons as one numbered list and lets the user pick the
+// episodes which are downloaded, e.g. "1,3,5-8". An empty input selects all shown episodes.
+func (series *Series) PrintAndGetEpisodeSelection(seasons []Season) ([]Season, error) {
+	var episodes []*Episode
+	printMenu := func() {
+		fmt.Println("Which Episodes do you want to download (e.g. 1,3,5-8, empty for all):")
+
+		for idx, episode := range episodes {
+			color.Cyan("  %d. %s", idx+1, episode.Name)
+		}
+	}
+
+	// The episodes are labeled with their season and episode number, since the list spans all seasons
+	for _, season := range seasons {
+		for _, episode := range season.Episodes {
+			episode.Name = fmt.Sprintf("S%02dE%02d %s", season.Index, episode.Index, episode.Name)
+			episodes = append(episodes, &episode)
+		}
+	}
+
+	if len(episodes) == 0 {
+		return nil, errors.New("No episodes left to pick from")
+	}
+
+	printMenu()
+	choices, err := GetUserChoices(len(episodes), printMenu)
+	if errors.Is(err, ErrPromptDisabled) {
+		return nil, errors.New("Cannot pick episodes interactively when -yes is set. Pass -episodes instead.")
+	} else if err != nil {
+		return nil, err
+	}
+
+	selected := make(map[string]bool)
+	for _, choice := range choices {
+		selected[episodes[choice-1].Id] = true
+	}
+
+	return FilterEpisodes(seasons, func(season *Season, episode *Episode) bool {
+		return selected[episode.Id]
+	}), nil
+}
+
+// Prints the episodes which will be downloaded including their total size and asks for a
+// confirmation. Refuses if there is not enough disk space and prompts are disabled.
+func (series *Series) PrintAndGetConfirmation(seasonsToDownload []Season, opts DownloadOptions) bool {
 	fmt.Println("The following Episodes will be downloaded:")
 	color.Green(series.Name)
 
@@ -129,15 +256,206 @@ func (series *Series) PrintAndGetConfirmation(seasonsToDownload []Season) bool {
 		}
 	}
 
+	var episodes int
+	var total int64
+	for _, season := range seasonsToDownload {
+		episodes += len(season.Episodes)
+		total += season.GetSize()
+	}
+
+	color.Green("Total: %d episodes, %s", episodes, formatSize(total))
+	if opts.Extras {
+		printExtras(series.Extras)
+		total += getExtrasSize(series.Extras)
+	}
+
+	if !CheckDiskSpace(opts, total) {
+		return false
+	}
+
 	return GetConfirmation()
 }
 
-func (season *Season) Download(baseUrl string, token string) {
+// Selects the media source with the given id for every episode of the seasons which has it, see
+// UseSource. Returns false if none of the episodes has the source.
+func UseSourceInSeasons(seasons []Season, id string) bool {
+	found := false
+	for seasonIdx := range seasons {
+		for idx := range seasons[seasonIdx].Episodes {
+			if err := seasons[seasonIdx].Episodes[idx].UseSource(id); err == nil {
+				found = true
+			}
+		}
+	}
+
+	return found
+}
+
+// Returns the values filename templates are filled with for the given episode of the season.
+func (season *Season) GetTemplateValues(series *Series, idx int) TemplateValues {
+	return TemplateValues{
+		Series:  series.Name,
+		Season:  season.Index,
+		Episode: season.Episodes[idx].Index,
+		Title:   season.Episodes[idx].Name,
+		Year:    series.Year,
+	}
+}
+
+// Returns the filename (without extension) for the given episode of the season.
+func (season *Season) GetEpisodeFilename(series *Series, idx int, opts DownloadOptions) string {
+	episode := season.Episodes[idx]
+	template := opts.Template
+	if template == nil {
+		template = opts.Layout.GetEpisodeTemplate()
+	}
+
+	if template != nil {
+		return template.Format(season.GetTemplateValues(series, idx))
+	}
+
+	seasonid := strings.Split(season.Name, " ")
+	return SanitizeFilename(fmt.Sprintf("S%sE%d %s", seasonid[len(seasonid)-1], episode.Index, episode.Name))
+}
+
+// Returns the download jobs for all episodes of the season.
+func (season *Season) GetDownloadJobs(baseUrl string, token string, series *Series, opts DownloadOptions) []DownloadJob {
+	var jobs []DownloadJob
+	for _, group := range season.getEpisodeJobs(baseUrl, token, series, opts) {
+		jobs = append(jobs, group.jobs...)
+	}
+
+	return jobs
+}
+
+// Returns the download jobs of the season grouped by episode.
+func (season *Season) getEpisodeJobs(baseUrl string, token string, series *Series, opts DownloadOptions) []episodeJobs {
+	var groups []episodeJobs
 	for idx, episode := range season.Episodes {
-		suffix := strings.Split(episode.Container, ",")[0]
-		seasonid := strings.Split(season.Name, " ")
-		outfilename := fmt.Sprintf("S%sE%d %s.%s", seasonid[len(seasonid)-1], int(idx)+1, episode.Name, suffix)
-		downloadLink := GetDownloadLinkForId(baseUrl, token, episode.Id)
-		DownloadFromUrl(downloadLink, episode.Name, outfilename, len(season.Episodes), idx)
+		outfile := GetOutputPath(opts.OutputDir, filepath.Join(opts.Layout.GetEpisodeDir(series, season), season.GetEpisodeFilename(series, idx, opts)))
+		outfile = episode.getPreservedOutfile(outfile, opts)
+
+		// The first job is the episode itself, the others are its sidecar files
+		jobs := episode.GetDownloadJobs(baseUrl, token, outfile, opts)
+		values := season.GetTemplateValues(series, idx)
+		jobs[0].Episode = &values
+
+		if opts.Nfo {
+			jobs = append(jobs, GetEpisodeNfoJob(series, season, &episode, outfile))
+		}
+
+		groups = append(groups, episodeJobs{size: episode.Size, jobs: jobs})
 	}
+
+	return groups
+}
+
+// Downloads all episodes of the given seasons of the series in the order given by opts.Sort. If
+// opts.SeasonConcurrency is greater than 1, the seasons are downloaded in parallel and the order
+// only applies within each season.
+func DownloadEpisodes(ctx context.Context, baseUrl string, token string, series *Series, seasons []Season, opts DownloadOptions) []DownloadResult {
+	if opts.SeasonConcurrency <= 1 || len(seasons) <= 1 || opts.DryRun {
+		return DownloadJobs(ctx, GetEpisodeJobs(baseUrl, token, series, seasons, opts), opts)
+	}
+
+	results := downloadSeasons(ctx, seasons, getSeasonGroups(baseUrl, token, series, seasons, opts), opts)
+	return append(results, DownloadJobs(ctx, getSeriesJobs(baseUrl, token, series, seasons, opts), opts)...)
+}
+
+// Returns the jobs of all episodes of the given seasons in the order given by opts.Sort, followed
+// by the sidecar files of the series.
+func GetEpisodeJobs(baseUrl string, token string, series *Series, seasons []Season, opts DownloadOptions) []DownloadJob {
+	var groups []episodeJobs
+	for _, seasonGroup := range getSeasonGroups(baseUrl, token, series, seasons, opts) {
+		groups = append(groups, seasonGroup...)
+	}
+
+	return append(sortEpisodeJobs(groups, opts.Sort), getSeriesJobs(baseUrl, token, series, seasons, opts)...)
+}
+
+// Returns the jobs of the episodes of every season grouped by episode.
+func getSeasonGroups(baseUrl string, token string, series *Series, seasons []Season, opts DownloadOptions) [][]episodeJobs {
+	seasonGroups := make([][]episodeJobs, len(seasons))
+	for idx, season := range seasons {
+		for _, episode := range season.Episodes {
+			EmitItemEvent("Episode", episode.Id, episode.Name, map[string]any{
+				"series": series.Name, "season": season.Index, "episode": episode.Index, "size": episode.Size,
+			})
+		}
+
+		seasonGroups[idx] = season.getEpisodeJobs(baseUrl, token, series, opts)
+	}
+
+	return seasonGroups
+}
+
+// Returns the jobs of the extras, artwork and metadata of the series itself.
+func getSeriesJobs(baseUrl string, token string, series *Series, seasons []Season, opts DownloadOptions) []DownloadJob {
+	var jobs []DownloadJob
+	if opts.Extras {
+		jobs = append(jobs, getSeriesExtraJobs(baseUrl, token, series, opts)...)
+	}
+
+	if opts.Artwork {
+		jobs = append(jobs, GetSeriesArtworkJobs(baseUrl, token, series, seasons, opts)...)
+	}
+
+	if opts.Nfo {
+		jobs = append(jobs, GetSeriesNfoJob(series, opts))
+	}
+
+	return jobs
+}
+
+// Downloads the episodes of up to opts.SeasonConcurrency seasons in parallel. Every season is a
+// job of its own with a line in the progress view, which shows how many of its episodes are done.
+func downloadSeasons(ctx context.Context, seasons []Season, seasonGroups [][]episodeJobs, opts DownloadOptions) []DownloadResult {
+	progresses := make([]*seasonProgress, len(seasons))
+	for idx, season := range seasons {
+		progresses[idx] = &seasonProgress{name: season.Name, episodes: len(seasonGroups[idx])}
+		for _, group := range seasonGroups[idx] {
+			progresses[idx].size += group.size
+		}
+	}
+
+	view := startSeasonView(progresses)
+	defer view.Stop()
+
+	seasonResults := make([][]DownloadResult, len(seasons))
+	semaphore := make(chan struct{}, opts.SeasonConcurrency)
+	var wg sync.WaitGroup
+
+	for idx := range seasons {
+		// Once the context is cancelled, the remaining seasons are still passed to DownloadJobs,
+		// which marks their jobs as cancelled
+		acquired := false
+		select {
+		case semaphore <- struct{}{}:
+			acquired = true
+		case <-ctx.Done():
+		}
+
+		wg.Add(1)
+		go func(idx int) {
+			defer wg.Done()
+			if acquired {
+				defer func() { <-semaphore }()
+			}
+
+			seasonOpts := opts
+			seasonOpts.seasonProgress = progresses[idx]
+			progresses[idx].setActive(true)
+			seasonResults[idx] = DownloadJobs(ctx, sortEpisodeJobs(seasonGroups[idx], opts.Sort), seasonOpts)
+			view.finish(progresses[idx])
+		}(idx)
+	}
+
+	wg.Wait()
+
+	var results []DownloadResult
+	for _, seasonResult := range seasonResults {
+		results = append(results, seasonResult...)
+	}
+
+	return results
 }
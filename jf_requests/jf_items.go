@@ -1,15 +1,28 @@
 package jf_requests
 
 import (
+	"context"
 	"errors"
 	"fmt"
+	"net/url"
+	"slices"
 	"strings"
+	"time"
 )
 
 type Item struct {
 	Name string
 	Id   string
 	Type string
+	Year int
+	// Only known for items which were fetched with the fields Genres and Tags.
+	Genres []string
+	Tags   []string
+	// The series and the position of episodes; only set for items of the type Episode.
+	SeriesId   string
+	SeriesName string
+	Season     int
+	Episode    int
 }
 
 func GetItem(rawItems []any, parentItem *Item) []Item {
@@ -19,6 +32,15 @@ func GetItem(rawItems []any, parentItem *Item) []Item {
 			Name: item.(map[string]any)["Name"].(string),
 			Id:   item.(map[string]any)["Id"].(string),
 			Type: item.(map[string]any)["Id"].(string),
+			Year: GetIntFromRawItem(item.(map[string]any), "ProductionYear", 0),
+
+			Genres: GetStringListFromRawItem(item.(map[string]any), "Genres"),
+			Tags:   GetStringListFromRawItem(item.(map[string]any), "Tags"),
+
+			SeriesId:   GetStringFromRawItem(item.(map[string]any), "SeriesId"),
+			SeriesName: GetStringFromRawItem(item.(map[string]any), "SeriesName"),
+			Season:     GetIntFromRawItem(item.(map[string]any), "ParentIndexNumber", 0),
+			Episode:    GetIntFromRawItem(item.(map[string]any), "IndexNumber", 0),
 		}
 
 		if itmtype, ok := item.(map[string]any)["Type"].(string); ok {
@@ -35,11 +57,64 @@ func GetItem(rawItems []any, parentItem *Item) []Item {
 	return result
 }
 
+// Returns the size in bytes of the primary media source of the given raw item or 0 if unknown.
+func GetSizeFromRawItem(rawItem map[string]any) int64 {
+	sources, ok := rawItem["MediaSources"].([]any)
+	if !ok || len(sources) == 0 {
+		return 0
+	}
+
+	if size, ok := sources[0].(map[string]any)["Size"].(float64); ok {
+		return int64(size)
+	}
+
+	return 0
+}
+
+// Returns the integer value stored for key in the given raw item or fallback if it is missing.
+func GetIntFromRawItem(rawItem map[string]any, key string, fallback int) int {
+	if value, ok := rawItem[key].(float64); ok {
+		return int(value)
+	}
+
+	return fallback
+}
+
+// Returns the string value stored for key in the given raw item or an empty string if it is missing.
+func GetStringFromRawItem(rawItem map[string]any, key string) string {
+	value, _ := rawItem[key].(string)
+	return value
+}
+
+// Returns the strings stored as list for key in the given raw item or nil if it is missing.
+func GetStringListFromRawItem(rawItem map[string]any, key string) []string {
+	values, _ := rawItem[key].([]any)
+
+	var result []string
+	for _, value := range values {
+		if value, ok := value.(string); ok {
+			result = append(result, value)
+		}
+	}
+
+	return result
+}
+
+// Returns the time stored for key in the given raw item or the zero time if it is missing or invalid.
+func GetTimeFromRawItem(rawItem map[string]any, key string) time.Time {
+	value, err := time.Parse(time.RFC3339Nano, GetStringFromRawItem(rawItem, key))
+	if err != nil {
+		return time.Time{}
+	}
+
+	return value
+}
+
 // Returns all Root Items
-func GetRootItems(auth *AuthResponse, baseurl string) ([]Item, error) {
-	requestUrl := baseurl + fmt.Sprintf("/Users/%s/Items", auth.UserId)
+func GetRootItems(ctx context.Context, auth *AuthResponse, baseurl string) ([]Item, error) {
+	requestUrl := BuildUrl(baseurl, fmt.Sprintf("Users/%s/Items", auth.UserId), nil)
 
-	res, err := MakeRequest(auth.Token, requestUrl, "GET", nil)
+	res, err := MakeRequest(ctx, auth.Token, requestUrl, "GET", nil)
 	if err != nil {
 		return nil, err
 	}
@@ -48,10 +123,10 @@ func GetRootItems(auth *AuthResponse, baseurl string) ([]Item, error) {
 	return GetItem(items, nil), nil
 }
 
-func GetItemsForParentId(auth *AuthResponse, baseurl string, parentItem *Item) ([]Item, error) {
-	requestUrl := baseurl + fmt.Sprintf("/Users/%s/Items?ParentId=%s", auth.UserId, parentItem.Id)
+func GetItemsForParentId(ctx context.Context, auth *AuthResponse, baseurl string, parentItem *Item) ([]Item, error) {
+	requestUrl := BuildUrl(baseurl, fmt.Sprintf("Users/%s/Items", auth.UserId), url.Values{"ParentId": {parentItem.Id}, "Fields": {"Genres,Tags"}})
 
-	res, err := MakeRequest(auth.Token, requestUrl, "GET", nil)
+	res, err := MakeRequest(ctx, auth.Token, requestUrl, "GET", nil)
 	if err != nil {
 		return nil, err
 	}
@@ -61,15 +136,15 @@ func GetItemsForParentId(auth *AuthResponse, baseurl string, parentItem *Item) (
 }
 
 // Returns all items found on the given jellyfin server.
-func GetAllItems(auth *AuthResponse, baseurl string) ([]Item, error) {
-	rootItems, err := GetRootItems(auth, baseurl)
+func GetAllItems(ctx context.Context, auth *AuthResponse, baseurl string) ([]Item, error) {
+	rootItems, err := GetRootItems(ctx, auth, baseurl)
 	if err != nil {
 		return nil, err
 	}
 
 	var items []Item = make([]Item, 0, 256)
 	for _, rootItem := range rootItems {
-		childItems, err := GetItemsForParentId(auth, baseurl, &rootItem)
+		childItems, err := GetItemsForParentId(ctx, auth, baseurl, &rootItem)
 		if err != nil {
 			return nil, err
 		}
@@ -81,16 +156,43 @@ func GetAllItems(auth *AuthResponse, baseurl string) ([]Item, error) {
 
 }
 
-// Returns the item whose name includes the given search term.
-func GetItemsForText(auth *AuthResponse, baseUrl string, searchtext string) ([]Item, error) {
-	all, err := GetAllItems(auth, baseUrl)
+// Item types which can be selected for a download.
+var downloadableTypes = []string{"Series", "Movie", "BoxSet", "Playlist", "MusicAlbum", "Audio"}
+
+// Returns the name of the item including its type and year, e.g. "The Office (Series, 2005)".
+func (item *Item) GetDisplayName() string {
+	if item.Year == 0 {
+		return fmt.Sprintf("%s (%s)", item.Name, item.Type)
+	}
+
+	return fmt.Sprintf("%s (%s, %d)", item.Name, item.Type, item.Year)
+}
+
+// Returns the downloadable items whose name includes the given search term. Items which are found
+// multiple times, e.g. because they are part of multiple libraries, are only returned once. If
+// libraryId is set, only the items of this library are searched.
+func GetItemsForText(ctx context.Context, auth *AuthResponse, baseUrl string, searchtext string, libraryId string) ([]Item, error) {
+	var all []Item
+	var err error
+	if libraryId != "" {
+		all, err = GetItemsForParentId(ctx, auth, baseUrl, &Item{Id: libraryId})
+	} else {
+		all, err = GetAllItems(ctx, auth, baseUrl)
+	}
+
 	if err != nil {
 		return nil, err
 	}
 
 	var results []Item
+	seen := make(map[string]bool)
 	for _, item := range all {
+		if !slices.Contains(downloadableTypes, item.Type) || seen[item.Id] {
+			continue
+		}
+
 		if strings.Contains(strings.ToLower(item.Name), strings.ToLower(searchtext)) {
+			seen[item.Id] = true
 			results = append(results, item)
 		}
 	}
@@ -98,9 +200,9 @@ func GetItemsForText(auth *AuthResponse, baseUrl string, searchtext string) ([]I
 	return results, nil
 }
 
-func GetItemForId(auth *AuthResponse, baseurl string, id string) (*Item, error) {
-	requestUrl := baseurl + fmt.Sprintf("/Users/%s/Items/%s", auth.UserId, id)
-	res, err := MakeRequest(auth.Token, requestUrl, "GET", nil)
+func GetItemForId(ctx context.Context, auth *AuthResponse, baseurl string, id string) (*Item, error) {
+	requestUrl := BuildUrl(baseurl, fmt.Sprintf("Users/%s/Items/%s", auth.UserId, id), nil)
+	res, err := MakeRequest(ctx, auth.Token, requestUrl, "GET", nil)
 	if err != nil {
 		return nil, errors.New(fmt.Sprintf("Failed to find item with id: %s - %s", id, err))
 	}
@@ -109,3 +211,48 @@ func GetItemForId(auth *AuthResponse, baseurl string, id string) (*Item, error)
 	resList[0] = res
 	return &GetItem(resList, nil)[0], nil
 }
+
+// Names of the external databases under which Jellyfin stores the ids of the items.
+const (
+	ProviderImdb = "Imdb"
+	ProviderTvdb = "Tvdb"
+)
+
+// Returns the downloadable items which have the given id in the given external database, e.g.
+// ProviderImdb and "tt0303461". The ids of the returned items are checked as well, since older
+// servers ignore the provider filter of the request.
+func GetItemsForProviderId(ctx context.Context, auth *AuthResponse, baseUrl string, provider string, id string) ([]Item, error) {
+	params := url.Values{}
+	params.Set("Recursive", "true")
+	params.Set("Fields", "ProviderIds")
+	params.Set("IncludeItemTypes", strings.Join(downloadableTypes, ","))
+	params.Set("AnyProviderIdEquals", fmt.Sprintf("%s.%s", strings.ToLower(provider), id))
+	requestUrl := BuildUrl(baseUrl, fmt.Sprintf("Users/%s/Items", auth.UserId), params)
+
+	res, err := MakeRequest(ctx, auth.Token, requestUrl, "GET", nil)
+	if err != nil {
+		return nil, err
+	}
+
+	rawItems, _ := res["Items"].([]any)
+
+	var matching []any
+	seen := make(map[string]bool)
+	for _, rawItem := range rawItems {
+		providerIds, _ := rawItem.(map[string]any)["ProviderIds"].(map[string]any)
+		itemId := GetStringFromRawItem(rawItem.(map[string]any), "Id")
+		if seen[itemId] {
+			continue
+		}
+
+		for key, value := range providerIds {
+			if strings.EqualFold(key, provider) && strings.EqualFold(fmt.Sprint(value), id) {
+				seen[itemId] = true
+				matching = append(matching, rawItem)
+				break
+			}
+		}
+	}
+
+	return GetItem(matching, nil), nil
+}
@@ -0,0 +1,75 @@
+package jf_requests
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/url"
+	"strconv"
+)
+
+// Returns the items which were added last to the libraries of the user, newest first. If
+// libraryId is set, only the items of this library are returned. Episodes are returned one by
+// one instead of being grouped into their series, so only the new episodes are downloaded.
+func GetLatestItems(ctx context.Context, auth *AuthResponse, baseurl string, libraryId string, limit int) ([]Item, error) {
+	params := url.Values{}
+	params.Set("Limit", strconv.Itoa(limit))
+	params.Set("GroupItems", "false")
+	params.Set("Fields", "Genres,Tags")
+	if libraryId != "" {
+		params.Set("ParentId", libraryId)
+	}
+
+	requestUrl := BuildUrl(baseurl, fmt.Sprintf("Users/%s/Items/Latest", auth.UserId), params)
+	rawItems, err := MakeListRequest(ctx, auth.Token, requestUrl, "GET", nil)
+	if err != nil {
+		return nil, errors.New(fmt.Sprintf("Failed to obtain the latest items: %s", err))
+	}
+
+	return GetItem(rawItems, nil), nil
+}
+
+// Returns the items the user started watching but did not finish, i.e. the "Continue Watching"
+// list of the Jellyfin clients, the most recently watched first.
+func GetResumeItems(ctx context.Context, auth *AuthResponse, baseurl string, limit int) ([]Item, error) {
+	params := url.Values{}
+	params.Set("Limit", strconv.Itoa(limit))
+	params.Set("MediaTypes", "Video")
+	params.Set("Fields", "Genres,Tags")
+
+	requestUrl := BuildUrl(baseurl, fmt.Sprintf("Users/%s/Items/Resume", auth.UserId), params)
+	res, err := MakeRequest(ctx, auth.Token, requestUrl, "GET", nil)
+	if err != nil {
+		return nil, errors.New(fmt.Sprintf("Failed to obtain the items to continue watching: %s", err))
+	}
+
+	rawItems, _ := res["Items"].([]any)
+	return GetItem(rawItems, nil), nil
+}
+
+// Groups the episodes among the given items by their series, so they can be downloaded like the
+// episodes of a saved selection. The order of the series is the order of their first episode.
+// Returns the series and the items which are no episodes.
+func GroupEpisodesBySeries(items []Item) ([]SelectionSeries, []Item) {
+	var series []SelectionSeries
+	var others []Item
+	positions := make(map[string]int)
+
+	for _, item := range items {
+		if item.Type != "Episode" || item.SeriesId == "" {
+			others = append(others, item)
+			continue
+		}
+
+		idx, ok := positions[item.SeriesId]
+		if !ok {
+			idx = len(series)
+			positions[item.SeriesId] = idx
+			series = append(series, SelectionSeries{Id: item.SeriesId, Name: item.SeriesName})
+		}
+
+		series[idx].Episodes = append(series[idx].Episodes, SelectionEpisode{Id: item.Id, Name: item.Name, Season: item.Season, Episode: item.Episode})
+	}
+
+	return series, others
+}
@@ -0,0 +1,86 @@
+package jf_requests
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/fatih/color"
+)
+
+// A library of the server as shown to the user, e.g. "Shows" or "Movies".
+type Library struct {
+	Name string
+	Id   string
+	// Kind of the items in the library, e.g. "tvshows", "movies" or "music". Empty for mixed libraries.
+	CollectionType string
+}
+
+// Returns the libraries the user has access to.
+func GetLibraries(ctx context.Context, auth *AuthResponse, baseurl string) ([]Library, error) {
+	requestUrl := BuildUrl(baseurl, fmt.Sprintf("Users/%s/Views", auth.UserId), nil)
+	res, err := MakeRequest(ctx, auth.Token, requestUrl, "GET", nil)
+	if err != nil {
+		return nil, errors.New(fmt.Sprintf("Failed to obtain the libraries: %s", err))
+	}
+
+	var libraries []Library
+	rawItems, _ := res["Items"].([]any)
+	for _, rawItem := range rawItems {
+		rawLibrary := rawItem.(map[string]any)
+		libraries = append(libraries, Library{
+			Name:           GetStringFromRawItem(rawLibrary, "Name"),
+			Id:             GetStringFromRawItem(rawLibrary, "Id"),
+			CollectionType: GetStringFromRawItem(rawLibrary, "CollectionType"),
+		})
+	}
+
+	return libraries, nil
+}
+
+// Returns the library with the given id or name. Names are compared ignoring the case.
+func FindLibrary(libraries []Library, wanted string) (*Library, error) {
+	var names []string
+	for idx, library := range libraries {
+		if library.Id == wanted || strings.EqualFold(library.Name, wanted) {
+			return &libraries[idx], nil
+		}
+
+		names = append(names, library.Name)
+	}
+
+	return nil, errors.New(fmt.Sprintf("Library '%s' not found. Available libraries: %s", wanted, strings.Join(names, ", ")))
+}
+
+// Prints the libraries with their ids as table. In the JSON output mode an item event is emitted
+// for every library instead.
+func PrintLibraries(libraries []Library) {
+	if JsonOutputEnabled() {
+		for _, library := range libraries {
+			EmitItemEvent("Library", library.Id, library.Name, map[string]any{"collection_type": library.CollectionType})
+		}
+
+		return
+	}
+
+	if len(libraries) == 0 {
+		color.Yellow("The user has no access to any library")
+		return
+	}
+
+	writer := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(writer, "NAME\tTYPE\tID")
+	for _, library := range libraries {
+		collectionType := library.CollectionType
+		if collectionType == "" {
+			collectionType = "mixed"
+		}
+
+		fmt.Fprintf(writer, "%s\t%s\t%s\n", library.Name, collectionType, library.Id)
+	}
+
+	writer.Flush()
+}
@@ -0,0 +1,287 @@
+package jf_requests
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"slices"
+	"strings"
+	"time"
+)
+
+// Fields shared by all downloadable media items like episodes and movies.
+type MediaItem struct {
+	Name         string
+	Id           string
+	Container    string
+	Size         int64
+	MediaSources []MediaSource
+	Metadata     Metadata
+	// Time the item was added to the server; zero if unknown.
+	DateCreated time.Time
+	// Length of the item; zero if unknown.
+	RunTime   time.Duration
+	Chapters  []Chapter
+	Trickplay *TrickplayInfo
+	// Whether the user has watched the item. Only known for items which were fetched for a user.
+	Played bool
+	// Id of the media source selected by UseSource or UseVersion; empty for the primary source.
+	sourceId string
+}
+
+type MediaStream struct {
+	Index      int
+	Type       string
+	Codec      string
+	Language   string
+	Title      string
+	IsExternal bool
+	IsDefault  bool
+	IsForced   bool
+	// Resolution of video streams and channels of audio streams; 0 if unknown.
+	Width    int
+	Height   int
+	Channels int
+	// Bits per second; 0 if unknown.
+	BitRate int
+}
+
+type MediaSource struct {
+	Id        string
+	Name      string
+	Container string
+	Path      string
+	Size      int64
+	// Bits per second of all streams; 0 if unknown.
+	BitRate int
+	Streams []MediaStream
+}
+
+// Parses the MediaSources of the given raw item.
+func GetMediaSourcesFromRawItem(rawItem map[string]any) []MediaSource {
+	rawSources, _ := rawItem["MediaSources"].([]any)
+
+	var sources []MediaSource
+	for _, rawSource := range rawSources {
+		source := rawSource.(map[string]any)
+		mediaSource := MediaSource{
+			Id:        GetStringFromRawItem(source, "Id"),
+			Name:      GetStringFromRawItem(source, "Name"),
+			Container: GetStringFromRawItem(source, "Container"),
+			Path:      GetStringFromRawItem(source, "Path"),
+			BitRate:   GetIntFromRawItem(source, "Bitrate", 0),
+		}
+
+		if size, ok := source["Size"].(float64); ok {
+			mediaSource.Size = int64(size)
+		}
+
+		rawStreams, _ := source["MediaStreams"].([]any)
+		for _, rawStream := range rawStreams {
+			stream := rawStream.(map[string]any)
+			isExternal, _ := stream["IsExternal"].(bool)
+			isDefault, _ := stream["IsDefault"].(bool)
+			isForced, _ := stream["IsForced"].(bool)
+
+			mediaSource.Streams = append(mediaSource.Streams, MediaStream{
+				Index:      GetIntFromRawItem(stream, "Index", -1),
+				Type:       GetStringFromRawItem(stream, "Type"),
+				Codec:      GetStringFromRawItem(stream, "Codec"),
+				Language:   GetStringFromRawItem(stream, "Language"),
+				Title:      GetStringFromRawItem(stream, "DisplayTitle"),
+				IsExternal: isExternal,
+				IsDefault:  isDefault,
+				IsForced:   isForced,
+				Width:      GetIntFromRawItem(stream, "Width", 0),
+				Height:     GetIntFromRawItem(stream, "Height", 0),
+				Channels:   GetIntFromRawItem(stream, "Channels", 0),
+				BitRate:    GetIntFromRawItem(stream, "BitRate", 0),
+			})
+		}
+
+		sources = append(sources, mediaSource)
+	}
+
+	return sources
+}
+
+// Returns all streams of the given type ("Video", "Audio" or "Subtitle").
+func (source *MediaSource) GetStreams(streamType string) []MediaStream {
+	var streams []MediaStream
+	for _, stream := range source.Streams {
+		if stream.Type == streamType {
+			streams = append(streams, stream)
+		}
+	}
+
+	return streams
+}
+
+// Parses the fields shared by all media items from the given raw item.
+func GetMediaItemFromRawItem(rawItem map[string]any) MediaItem {
+	item := MediaItem{
+		Name:         GetStringFromRawItem(rawItem, "Name"),
+		Id:           GetStringFromRawItem(rawItem, "Id"),
+		Container:    GetStringFromRawItem(rawItem, "Container"),
+		Size:         GetSizeFromRawItem(rawItem),
+		MediaSources: GetMediaSourcesFromRawItem(rawItem),
+		Metadata:     GetMetadataFromRawItem(rawItem),
+		DateCreated:  GetTimeFromRawItem(rawItem, "DateCreated"),
+		RunTime:      GetRunTimeFromRawItem(rawItem),
+		Chapters:     GetChaptersFromRawItem(rawItem),
+	}
+
+	if userData, ok := rawItem["UserData"].(map[string]any); ok {
+		item.Played, _ = userData["Played"].(bool)
+	}
+
+	if source := item.GetPrimarySource(); source != nil {
+		item.Trickplay = GetTrickplayFromRawItem(rawItem, source.Id)
+	}
+
+	return item
+}
+
+// Returns the primary media source of the item or nil if the item has none.
+func (item *MediaItem) GetPrimarySource() *MediaSource {
+	if len(item.MediaSources) == 0 {
+		return nil
+	}
+
+	return &item.MediaSources[0]
+}
+
+// Selects the media source with the given id, e.g. one of multiple encodes of the same title, which
+// is downloaded instead of the primary one.
+func (item *MediaItem) UseSource(id string) error {
+	var sources []string
+	for idx, source := range item.MediaSources {
+		if source.Id == id {
+			item.useSourceAt(idx)
+			return nil
+		}
+
+		sources = append(sources, source.Id)
+	}
+
+	return errors.New(fmt.Sprintf("Media source '%s' of %s not found. Available sources: %s", id, item.Name, strings.Join(sources, ", ")))
+}
+
+// Checks if the item has a media source with the given id.
+func (item *MediaItem) HasSource(id string) bool {
+	return slices.ContainsFunc(item.MediaSources, func(source MediaSource) bool { return source.Id == id })
+}
+
+// Makes the media source at the given position the primary source of the item.
+func (item *MediaItem) useSourceAt(idx int) {
+	source := item.MediaSources[idx]
+	item.MediaSources = append([]MediaSource{source}, append(item.MediaSources[:idx:idx], item.MediaSources[idx+1:]...)...)
+	item.Size = source.Size
+	if source.Container != "" {
+		item.Container = source.Container
+	}
+
+	item.sourceId = source.Id
+}
+
+// Returns the subtitles of the source which are downloaded. With preferred languages and without
+// explicit subtitle languages, only the most preferred subtitles are downloaded.
+func (item *MediaItem) selectSubtitles(source *MediaSource, opts DownloadOptions) map[string]MediaStream {
+	if len(opts.SubtitleLanguages) > 0 || len(opts.Languages) == 0 || opts.AllSubtitles {
+		return SelectSubtitles(source, opts.SubtitleLanguages)
+	}
+
+	subtitles, reason := SelectPreferredSubtitle(source, opts.Languages)
+	for language := range subtitles {
+		slog.Info(fmt.Sprintf("Selected %s subtitles of %s: %s", language, item.Name, reason))
+	}
+
+	return subtitles
+}
+
+// Returns the download jobs for the item and its sidecar files. outfile is the path of the
+// downloaded file without extension, the extension is derived from the downloaded format.
+func (item *MediaItem) GetDownloadJobs(baseUrl string, token string, outfile string, opts DownloadOptions) []DownloadJob {
+	// Alternate versions are items of their own, which share the id with their media source
+	if item.sourceId != "" && item.sourceId != item.Id {
+		selected := *item
+		selected.Id = item.sourceId
+		item = &selected
+	}
+
+	outfile = item.getPreservedOutfile(outfile, opts)
+	job := DownloadJob{
+		Name:  item.Name,
+		Url:   GetDownloadLinkForId(baseUrl, token, item.Id),
+		Size:  item.Size,
+		Media: item,
+	}
+
+	extension := GetMediaExtension(item)
+	if opts.Extension != "" {
+		extension = opts.Extension
+	}
+
+	source := item.GetPrimarySource()
+
+	if source != nil {
+		job.AudioTracks = FormatAudioStreams(source.GetStreams("Audio"))
+	}
+
+	if opts.Quality != nil || opts.Container != "" {
+		audioStreamIndex := -1
+		if opts.AudioTrack != "" && source != nil {
+			if stream, err := SelectAudioStream(source, opts.AudioTrack); err == nil {
+				audioStreamIndex = stream.Index
+				job.AudioTracks = FormatAudioStream(*stream)
+			} else {
+				job.Err = err
+			}
+		} else if (len(opts.Languages) > 0 || opts.PreferOriginalAudio) && source != nil {
+			if stream, reason := SelectPreferredAudioStream(source, opts.Languages, opts.PreferOriginalAudio); stream != nil {
+				slog.Info(fmt.Sprintf("Selected audio track %s of %s: %s", FormatAudioStream(*stream), item.Name, reason))
+				audioStreamIndex = stream.Index
+				job.AudioTracks = FormatAudioStream(*stream)
+			}
+		}
+
+		container := opts.Container
+		if container == "" {
+			container = TranscodeContainer
+		}
+
+		if opts.Quality != nil {
+			sourceId := ""
+			if source != nil {
+				sourceId = source.Id
+			}
+
+			job.Url = GetTranscodeLinkForId(baseUrl, token, item.Id, sourceId, opts.Quality, container, audioStreamIndex)
+		} else {
+			job.Url = GetRemuxLinkForId(baseUrl, token, item.Id, source, container, audioStreamIndex)
+		}
+
+		// The size of transcoded and remuxed streams is not known in advance
+		job.Size = 0
+		extension = container
+	}
+
+	job.Outfile = fmt.Sprintf("%s.%s", outfile, extension)
+
+	jobs := []DownloadJob{job}
+	if opts.Subtitles && source != nil {
+		jobs = append(jobs, GetSubtitleJobs(baseUrl, token, item.Id, item.Name, source, job.Outfile, item.selectSubtitles(source, opts))...)
+	}
+
+	if opts.Chapters {
+		if chapterJob := item.GetChapterJob(job.Outfile); chapterJob != nil {
+			jobs = append(jobs, *chapterJob)
+		}
+	}
+
+	if opts.Trickplay {
+		jobs = append(jobs, item.GetTrickplayJobs(baseUrl, token, job.Outfile)...)
+	}
+
+	return jobs
+}
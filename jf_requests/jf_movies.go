@@ -1,24 +1,27 @@
 package jf_requests
 
 import (
+	"context"
 	"errors"
 	"fmt"
-	"strings"
 
 	"github.com/fatih/color"
 )
 
 type Movie struct {
-	Name         string
-	Id           string
-	Container    string
+	MediaItem
+	Year         int
 	DownloadLink string
+	// Only loaded by LoadExtras.
+	Extras []Extra
+	// Name of the collection the movie is downloaded with, which is written to its nfo file as set.
+	Set string
 }
 
-func GetMovieFromItem(auth *AuthResponse, baseurl string, item *Item) (*Movie, error) {
-	requestUrl := fmt.Sprintf("%s/Users/%s/Items/%s", baseurl, auth.UserId, item.Id)
+func GetMovieFromItem(ctx context.Context, auth *AuthResponse, baseurl string, item *Item) (*Movie, error) {
+	requestUrl := BuildUrl(baseurl, fmt.Sprintf("Users/%s/Items/%s", auth.UserId, item.Id), nil)
 
-	res, err := MakeRequest(auth.Token, requestUrl, "GET", nil)
+	res, err := MakeRequest(ctx, auth.Token, requestUrl, "GET", nil)
 	if err != nil {
 		return nil, err
 	}
@@ -30,9 +33,8 @@ func GetMovieFromItem(auth *AuthResponse, baseurl string, item *Item) (*Movie, e
 	}
 
 	mov := Movie{
-		Name:         res["Name"].(string),
-		Id:           res["Id"].(string),
-		Container:    res["Container"].(string),
+		MediaItem:    GetMediaItemFromRawItem(res),
+		Year:         GetIntFromRawItem(res, "ProductionYear", 0),
 		DownloadLink: ""}
 
 	mov.DownloadLink = GetDownloadLinkForId(baseurl, auth.Token, mov.Id)
@@ -40,15 +42,62 @@ func GetMovieFromItem(auth *AuthResponse, baseurl string, item *Item) (*Movie, e
 	return &mov, nil
 }
 
-func (movie *Movie) PrintAndGetConfirmation() bool {
+// Returns the combined size of the loaded extras.
+func (movie *Movie) GetExtrasSize() int64 {
+	return getExtrasSize(movie.Extras)
+}
+
+// Prints the movie which will be downloaded including its size and asks for a confirmation.
+// Refuses if there is not enough disk space and prompts are disabled.
+func (movie *Movie) PrintAndGetConfirmation(opts DownloadOptions) bool {
 	fmt.Println("The following Movie will be downloaded:")
 	color.Green("Name: %s", movie.Name)
+	color.Green("Size: %s", formatSize(movie.Size))
+	if len(movie.MediaSources) > 1 {
+		color.Green("Version: %s (choose another one with -movie-version)", movie.MediaSources[0].Name)
+		for idx, source := range movie.MediaSources {
+			color.Cyan("  └ %s", formatVersion(idx, source))
+		}
+	}
+
+	if opts.Extras {
+		printExtras(movie.Extras)
+	}
+
+	if !CheckDiskSpace(opts, movie.Size+movie.GetExtrasSize()) {
+		return false
+	}
 
 	return GetConfirmation()
 }
 
-func (movie *Movie) Download() {
-	suffix := strings.Split(movie.Container, ",")[0]
-	outfilename := fmt.Sprintf("%s_%s.%s", movie.Name, movie.Name, suffix)
-	DownloadFromUrl(movie.DownloadLink, movie.Name, outfilename, 1, 0)
+func (movie *Movie) Download(ctx context.Context, baseUrl string, token string, opts DownloadOptions) []DownloadResult {
+	return DownloadJobs(ctx, movie.GetJobs(baseUrl, token, opts), opts)
+}
+
+// Returns the jobs of the movie, its extras and sidecar files.
+func (movie *Movie) GetJobs(baseUrl string, token string, opts DownloadOptions) []DownloadJob {
+	EmitItemEvent("Movie", movie.Id, movie.Name, map[string]any{"year": movie.Year, "size": movie.Size})
+
+	outfile := movie.getPreservedOutfile(GetOutputPath(opts.OutputDir, opts.Layout.GetMoviePath(movie)), opts)
+	jobs := movie.GetDownloadJobs(baseUrl, token, outfile, opts)
+	for idx := range jobs {
+		jobs[idx].Year = movie.Year
+	}
+
+	if opts.Extras {
+		for _, extra := range movie.Extras {
+			jobs = append(jobs, extra.GetDownloadJobs(baseUrl, token, extra.getOutfile(outfile, opts.Layout, opts.FlattenExtras), opts)...)
+		}
+	}
+
+	if opts.Artwork {
+		jobs = append(jobs, GetMovieArtworkJobs(baseUrl, token, movie, opts)...)
+	}
+
+	if opts.Nfo {
+		jobs = append(jobs, GetMovieNfoJob(movie, opts))
+	}
+
+	return jobs
 }
@@ -0,0 +1,235 @@
+package jf_requests
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/url"
+	"path/filepath"
+
+	"github.com/fatih/color"
+)
+
+// A single audio track, either of an album or downloaded on its own.
+type Track struct {
+	MediaItem
+	// Track and disc number; 0 if unknown.
+	Number int
+	Disc   int
+	Album  string
+	Artist string
+}
+
+// A music album with its tracks, sorted by disc and track number.
+type Album struct {
+	Name   string
+	Id     string
+	Artist string
+	Year   int
+	Tracks []Track
+}
+
+func getTrackFromRawItem(rawItem map[string]any) Track {
+	track := Track{
+		MediaItem: GetMediaItemFromRawItem(rawItem),
+		Number:    GetIntFromRawItem(rawItem, "IndexNumber", 0),
+		Disc:      GetIntFromRawItem(rawItem, "ParentIndexNumber", 0),
+		Album:     GetStringFromRawItem(rawItem, "Album"),
+		Artist:    GetStringFromRawItem(rawItem, "AlbumArtist"),
+	}
+
+	if artists, ok := rawItem["Artists"].([]any); ok && len(artists) > 0 && track.Artist == "" {
+		track.Artist, _ = artists[0].(string)
+	}
+
+	return track
+}
+
+// Fetches the tracks of the given album. Tracks without a media file are skipped and their names
+// are returned separately.
+func GetAlbumFromItem(ctx context.Context, auth *AuthResponse, baseurl string, item *Item) (*Album, []string, error) {
+	params := url.Values{}
+	params.Set("ParentId", item.Id)
+	params.Set("UserId", auth.UserId)
+	params.Set("IncludeItemTypes", "Audio")
+	params.Set("Recursive", "true")
+	params.Set("SortBy", "ParentIndexNumber,IndexNumber,SortName")
+	params.Set("Fields", mediaItemFields)
+	requestUrl := BuildUrl(baseurl, "Items", params)
+
+	res, err := MakeRequest(ctx, auth.Token, requestUrl, "GET", nil)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	album := Album{Name: item.Name, Id: item.Id, Year: item.Year}
+	var skipped []string
+
+	rawItems, _ := res["Items"].([]any)
+	for _, rawItem := range rawItems {
+		rawTrack := rawItem.(map[string]any)
+		if _, ok := rawTrack["Container"].(string); !ok {
+			skipped = append(skipped, GetStringFromRawItem(rawTrack, "Name"))
+			continue
+		}
+
+		track := getTrackFromRawItem(rawTrack)
+		if album.Artist == "" {
+			album.Artist = track.Artist
+		}
+
+		album.Tracks = append(album.Tracks, track)
+	}
+
+	return &album, skipped, nil
+}
+
+// Fetches the given audio track.
+func GetTrackFromItem(ctx context.Context, auth *AuthResponse, baseurl string, item *Item) (*Track, error) {
+	requestUrl := BuildUrl(baseurl, fmt.Sprintf("Users/%s/Items/%s", auth.UserId, item.Id), nil)
+
+	res, err := MakeRequest(ctx, auth.Token, requestUrl, "GET", nil)
+	if err != nil {
+		return nil, err
+	}
+
+	if res["Container"] == nil {
+		return nil, errors.New(fmt.Sprintf("Could not get container format for requested track; Might be missing or corrupted!"))
+	}
+
+	track := getTrackFromRawItem(res)
+	return &track, nil
+}
+
+// Returns the directory the tracks of an album are stored in, e.g. "Artist - Album".
+func getAlbumDirectory(artist string, album string) string {
+	if album == "" {
+		return SanitizeFilename(artist)
+	} else if artist == "" {
+		return SanitizeFilename(album)
+	}
+
+	return SanitizeFilename(fmt.Sprintf("%s - %s", artist, album))
+}
+
+// Returns the filename of the track without extension, e.g. "03 - Title". position is used if the
+// track number is unknown. If the album has multiple discs, the disc number is prepended, e.g. "2-03 - Title".
+func (track *Track) GetFilename(position int, multiDisc bool) string {
+	number := track.Number
+	if number == 0 {
+		number = position
+	}
+
+	if multiDisc && track.Disc > 0 {
+		return SanitizeFilename(fmt.Sprintf("%d-%02d - %s", track.Disc, number, track.Name))
+	}
+
+	return SanitizeFilename(fmt.Sprintf("%02d - %s", number, track.Name))
+}
+
+// Returns the options for downloading music. Audio files are always downloaded as they are, the
+// video specific options like transcoding and subtitles do not apply to them.
+func getMusicOptions(opts DownloadOptions) DownloadOptions {
+	opts.Quality = nil
+	opts.Container = ""
+	opts.Subtitles = false
+	opts.Chapters = false
+	opts.Trickplay = false
+	return opts
+}
+
+// Returns the download jobs of the track, stored at outfile without extension.
+func (track *Track) getDownloadJobs(baseUrl string, token string, outfile string, opts DownloadOptions) []DownloadJob {
+	return track.GetDownloadJobs(baseUrl, token, outfile, getMusicOptions(opts))
+}
+
+// Returns true if the tracks of the album are spread across multiple discs.
+func (album *Album) isMultiDisc() bool {
+	for _, track := range album.Tracks {
+		if track.Disc > 1 {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Returns the combined size of all tracks of the album.
+func (album *Album) GetSize() int64 {
+	var total int64 = 0
+	for _, track := range album.Tracks {
+		total += track.Size
+	}
+
+	return total
+}
+
+// Prints the album which will be downloaded and asks for a confirmation. Refuses if there is
+// not enough disk space and prompts are disabled.
+func (album *Album) PrintAndGetConfirmation(opts DownloadOptions) bool {
+	fmt.Println("The following Album will be downloaded:")
+	color.Green("%s (%d tracks, %s)", getAlbumDirectory(album.Artist, album.Name), len(album.Tracks), formatSize(album.GetSize()))
+
+	multiDisc := album.isMultiDisc()
+	for idx, track := range album.Tracks {
+		color.Cyan("  └ %s", track.GetFilename(idx+1, multiDisc))
+	}
+
+	if !CheckDiskSpace(getMusicOptions(opts), album.GetSize()) {
+		return false
+	}
+
+	return GetConfirmation()
+}
+
+// Downloads all tracks of the album into a directory named after the artist and the album.
+func (album *Album) Download(ctx context.Context, baseUrl string, token string, opts DownloadOptions) []DownloadResult {
+	return DownloadJobs(ctx, album.GetJobs(baseUrl, token, opts), opts)
+}
+
+// Returns the jobs of all tracks of the album.
+func (album *Album) GetJobs(baseUrl string, token string, opts DownloadOptions) []DownloadJob {
+	dir := GetOutputPath(opts.OutputDir, getAlbumDirectory(album.Artist, album.Name))
+	multiDisc := album.isMultiDisc()
+
+	var jobs []DownloadJob
+	for idx, track := range album.Tracks {
+		EmitItemEvent("Audio", track.Id, track.Name, map[string]any{"album": album.Name, "track": track.Number, "size": track.Size})
+
+		outfile := filepath.Join(dir, track.GetFilename(idx+1, multiDisc))
+		jobs = append(jobs, track.getDownloadJobs(baseUrl, token, outfile, opts)...)
+	}
+
+	return jobs
+}
+
+// Prints the track which will be downloaded including its size and asks for a confirmation.
+// Refuses if there is not enough disk space and prompts are disabled.
+func (track *Track) PrintAndGetConfirmation(opts DownloadOptions) bool {
+	fmt.Println("The following Track will be downloaded:")
+	color.Green("Name: %s", track.Name)
+	if track.Artist != "" {
+		color.Green("Artist: %s", track.Artist)
+	}
+	color.Green("Size: %s", formatSize(track.Size))
+
+	if !CheckDiskSpace(getMusicOptions(opts), track.Size) {
+		return false
+	}
+
+	return GetConfirmation()
+}
+
+// Downloads the track into the directory of its album.
+func (track *Track) Download(ctx context.Context, baseUrl string, token string, opts DownloadOptions) []DownloadResult {
+	return DownloadJobs(ctx, track.GetJobs(baseUrl, token, opts), opts)
+}
+
+// Returns the jobs of the track.
+func (track *Track) GetJobs(baseUrl string, token string, opts DownloadOptions) []DownloadJob {
+	EmitItemEvent("Audio", track.Id, track.Name, map[string]any{"album": track.Album, "track": track.Number, "size": track.Size})
+
+	dir := GetOutputPath(opts.OutputDir, getAlbumDirectory(track.Artist, track.Album))
+	outfile := filepath.Join(dir, track.GetFilename(1, false))
+	return track.getDownloadJobs(baseUrl, token, outfile, opts)
+}
@@ -0,0 +1,142 @@
+package jf_requests
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"path/filepath"
+
+	"github.com/fatih/color"
+)
+
+// A single movie or episode of a playlist.
+type PlaylistEntry struct {
+	MediaItem
+	Type string
+	Year int
+	// Series, season and episode number; only set for episodes.
+	Series  string
+	Season  int
+	Episode int
+}
+
+// A playlist with its entries in the order of the playlist.
+type Playlist struct {
+	Name    string
+	Id      string
+	Entries []PlaylistEntry
+}
+
+// Fetches the entries of the given playlist. Entries which cannot be downloaded, e.g. because
+// they have no media file, are skipped and their names are returned separately.
+func GetPlaylistFromItem(ctx context.Context, auth *AuthResponse, baseurl string, item *Item) (*Playlist, []string, error) {
+	requestUrl := BuildUrl(baseurl, fmt.Sprintf("Playlists/%s/Items", item.Id), url.Values{"UserId": {auth.UserId}, "Fields": {mediaItemFields}})
+
+	res, err := MakeRequest(ctx, auth.Token, requestUrl, "GET", nil)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	playlist := Playlist{Name: item.Name, Id: item.Id}
+	var skipped []string
+
+	rawItems, _ := res["Items"].([]any)
+	for _, rawItem := range rawItems {
+		rawEntry := rawItem.(map[string]any)
+		if _, ok := rawEntry["Container"].(string); !ok {
+			skipped = append(skipped, GetStringFromRawItem(rawEntry, "Name"))
+			continue
+		}
+
+		playlist.Entries = append(playlist.Entries, PlaylistEntry{
+			MediaItem: GetMediaItemFromRawItem(rawEntry),
+			Type:      GetStringFromRawItem(rawEntry, "Type"),
+			Year:      GetIntFromRawItem(rawEntry, "ProductionYear", 0),
+			Series:    GetStringFromRawItem(rawEntry, "SeriesName"),
+			Season:    GetIntFromRawItem(rawEntry, "ParentIndexNumber", 0),
+			Episode:   GetIntFromRawItem(rawEntry, "IndexNumber", 0),
+		})
+	}
+
+	return &playlist, skipped, nil
+}
+
+// Returns the combined size of all entries of the playlist.
+func (playlist *Playlist) GetSize() int64 {
+	var total int64 = 0
+	for _, entry := range playlist.Entries {
+		total += entry.Size
+	}
+
+	return total
+}
+
+// Returns the filename (without extension) of the entry at the given position. Episodes are named
+// like "Series - S01E05 - Title" or by the template, movies like "Name (Year)". If opts.PlaylistIndex
+// is set, the position is prepended, so the files are sorted in the order of the playlist.
+func (playlist *Playlist) GetEntryFilename(idx int, opts DownloadOptions) string {
+	entry := playlist.Entries[idx]
+
+	var filename string
+	if entry.Type == "Episode" {
+		template := opts.Template
+		if template == nil {
+			template, _ = ParseFilenameTemplate("{series} - S{season:02d}E{episode:02d} - {title}")
+		}
+
+		filename = template.Format(TemplateValues{
+			Series:  entry.Series,
+			Season:  entry.Season,
+			Episode: entry.Episode,
+			Title:   entry.Name,
+			Year:    entry.Year,
+		})
+	} else {
+		filename = getNameWithYear(entry.Name, entry.Year)
+	}
+
+	if opts.PlaylistIndex {
+		// Pad the index to the number of digits of the last index to keep the order when sorting by name
+		width := len(fmt.Sprint(len(playlist.Entries)))
+		filename = fmt.Sprintf("%0*d - %s", max(width, 2), idx+1, filename)
+	}
+
+	return filename
+}
+
+// Prints the playlist which will be downloaded and asks for a confirmation. Refuses if there is
+// not enough disk space and prompts are disabled.
+func (playlist *Playlist) PrintAndGetConfirmation(opts DownloadOptions) bool {
+	fmt.Println("The following Playlist will be downloaded:")
+	color.Green("%s (%d items, %s)", playlist.Name, len(playlist.Entries), formatSize(playlist.GetSize()))
+
+	for idx := range playlist.Entries {
+		color.Cyan("  └ %d. %s", idx+1, playlist.GetEntryFilename(idx, opts))
+	}
+
+	if !CheckDiskSpace(opts, playlist.GetSize()) {
+		return false
+	}
+
+	return GetConfirmation()
+}
+
+// Downloads all entries of the playlist into a directory named after the playlist.
+func (playlist *Playlist) Download(ctx context.Context, baseUrl string, token string, opts DownloadOptions) []DownloadResult {
+	return DownloadJobs(ctx, playlist.GetJobs(baseUrl, token, opts), opts)
+}
+
+// Returns the jobs of all entries of the playlist.
+func (playlist *Playlist) GetJobs(baseUrl string, token string, opts DownloadOptions) []DownloadJob {
+	dir := GetOutputPath(opts.OutputDir, SanitizeFilename(playlist.Name))
+
+	var jobs []DownloadJob
+	for idx, entry := range playlist.Entries {
+		EmitItemEvent(entry.Type, entry.Id, entry.Name, map[string]any{"playlist": playlist.Name, "position": idx + 1, "size": entry.Size})
+
+		outfile := filepath.Join(dir, playlist.GetEntryFilename(idx, opts))
+		jobs = append(jobs, entry.GetDownloadJobs(baseUrl, token, outfile, opts)...)
+	}
+
+	return jobs
+}
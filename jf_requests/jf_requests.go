@@ -2,14 +2,16 @@ package jf_requests
 
 import (
 	"bytes"
-	"crypto/tls"
+	"context"
 	"encoding/json"
 	"errors"
 	"fmt"
 	"io"
 	"log/slog"
 	"net/http"
+	"net/url"
 	"strings"
+	"time"
 )
 
 type AuthRequestBody struct {
@@ -22,10 +24,60 @@ type AuthResponse struct {
 	UserId string
 }
 
+// Executes the given request and returns the parsed JSON object of the response. Transient failures are retried.
 func ExecuteRequest(request *http.Request) (map[string]any, error) {
+	var content_json map[string]any
+	if err := executeRequestAndParse(request, &content_json); err != nil {
+		return nil, err
+	}
+
+	return content_json, nil
+}
+
+// Executes the given request and returns the parsed JSON list of the response. Transient failures are retried.
+func ExecuteListRequest(request *http.Request) ([]any, error) {
+	var content_json []any
+	if err := executeRequestAndParse(request, &content_json); err != nil {
+		return nil, err
+	}
+
+	return content_json, nil
+}
+
+func executeRequestAndParse(request *http.Request, result any) error {
+	ctx := request.Context()
+	content_raw, err := withReauthentication(ctx, getRequestToken(request), func(token string) ([]byte, error) {
+		if token != getRequestToken(request) {
+			setRequestToken(request, token)
+		}
+
+		return WithRetry(ctx, fmt.Sprintf("Request against %s", request.URL.Path), func() ([]byte, error) {
+			attempt := request.Clone(ctx)
+			if request.GetBody != nil {
+				attempt.Body, _ = request.GetBody()
+			}
+
+			return executeRequestOnce(attempt)
+		})
+	})
+
+	if err != nil {
+		return err
+	}
+
+	if err := json.Unmarshal(content_raw, result); err != nil {
+		return errors.New(fmt.Sprintf("Failed to Parse JSON from Response: %s", err))
+	}
+
+	return nil
+}
+
+func executeRequestOnce(request *http.Request) ([]byte, error) {
+	redactedUrl := RedactUrl(request.URL.String())
+
 	// Hide Authentication Request Log Output
-	if strings.Contains(request.URL.Path, "AuthenticateByName") {
-		slog.Debug("**AuthenticateByName request hidden**")
+	if isSensitivePath(request.URL.Path) {
+		slog.Debug(fmt.Sprintf("Executing Request against: %s", redactedUrl), "method", request.Method, "body", "**hidden**")
 	} else {
 		// Hide Authorization Header which would otherwise leak the auth token.
 		// In order to keep the original token in the referenced header in tact, we need to copy the
@@ -37,68 +89,60 @@ func ExecuteRequest(request *http.Request) (map[string]any, error) {
 		}
 
 		headerForPrinting["X-Emby-Authorization"][0] = "*****"
-		slog.Debug(fmt.Sprintf("Executing Request against: %s", request.URL), "method", request.Method, "header", headerForPrinting, "body", request.Body)
+		if _, ok := headerForPrinting["X-Emby-Token"]; ok {
+			headerForPrinting["X-Emby-Token"][0] = "*****"
+		}
+		slog.Debug(fmt.Sprintf("Executing Request against: %s", redactedUrl), "method", request.Method, "header", headerForPrinting)
 	}
 
-	client := &http.Client{}
-	res, err := client.Do(request)
+	if err := waitForApiLimit(request.Context()); err != nil {
+		return nil, err
+	}
+
+	start := time.Now()
+	res, err := httpClient.Do(request)
 
 	if err != nil {
-		return nil, errors.New(fmt.Sprintf("Request Failed: %s", err))
+		err = redactError(err)
+		slog.Debug(fmt.Sprintf("Request against %s failed", redactedUrl), "method", request.Method, "duration", time.Since(start), "error", err)
+		return nil, &ConnectionError{Err: err}
 	}
 
+	slog.Debug(fmt.Sprintf("Response from %s", redactedUrl), "method", request.Method, "status", res.StatusCode, "duration", time.Since(start))
+
 	defer res.Body.Close()
 
 	var content_raw []byte
 	content_raw, err = io.ReadAll(res.Body)
 
 	if err != nil {
-		return nil, errors.New(fmt.Sprintf("Could not read response body: %s", err))
+		return nil, &ConnectionError{Err: errors.New(fmt.Sprintf("Could not read response body: %s", err))}
 	} else if res.StatusCode != 200 {
-		slog.Debug(fmt.Sprintf("Request to %s returned a non 200 response code", request.RequestURI), "code", res.StatusCode, "response", string(content_raw[:]))
-		return nil, errors.New(fmt.Sprintf("Request Failed (Code %d): %s", res.StatusCode, content_raw))
-	}
-
-	var content_json map[string]any
-	err = json.Unmarshal(content_raw, &content_json)
-	if err != nil {
-		return nil, errors.New(fmt.Sprintf("Failed to Parse JSON from Response: %s", err))
+		slog.Debug(fmt.Sprintf("Request to %s returned a non 200 response code", redactedUrl), "code", res.StatusCode, "response", string(content_raw[:]))
+		return nil, newStatusError(res, string(content_raw))
 	}
 
 	// Hide Authentication Response Log Output
-	if strings.Contains(request.URL.Path, "AuthenticateByName") {
-		slog.Debug("**AuthenticateByName response hidden**")
-	} else {
-		slog.Debug("request result", "url", request.URL, "response header", res.Header, "body", string(content_raw[:]))
+	if !isSensitivePath(request.URL.Path) {
+		slog.Debug("request result", "url", redactedUrl, "response header", res.Header, "body", string(content_raw[:]))
 	}
 
-	return content_json, nil
-
+	return content_raw, nil
 }
 
 // Authorizes the given user with the provided password against the given Jellyfin hostname
 // When successfull, an auth token wich can be used for further requests is returned.
-func Authorize(baseUrl string, username string, password string) (*AuthResponse, error) {
-	http.DefaultTransport.(*http.Transport).TLSClientConfig = &tls.Config{InsecureSkipVerify: true}
-
-	sanitizedBaseUrl := baseUrl
-	// Strip the leading / from the baseurl, if there is any
-	if string(baseUrl[len(baseUrl)-1]) == "/" {
-		sanitizedBaseUrl = baseUrl[:len(baseUrl)-1]
-	}
-
-	requestUrl := fmt.Sprintf("%s/Users/AuthenticateByName", sanitizedBaseUrl)
+func Authorize(ctx context.Context, baseUrl string, username string, password string) (*AuthResponse, error) {
+	requestUrl := BuildUrl(baseUrl, "Users/AuthenticateByName", nil)
 
 	// Create Request Body with Credentials
 	reqbody := &AuthRequestBody{Username: username, Pw: password}
 	reqbody_json, err := json.Marshal(reqbody)
 
-	req, err := http.NewRequest("POST", requestUrl, bytes.NewBuffer(reqbody_json))
+	req, err := http.NewRequestWithContext(ctx, "POST", requestUrl, bytes.NewBuffer(reqbody_json))
 	req.Header.Set("Content-Type", "application/json")
 
-	// Fix Header by inserting the Authorization header with artificial Values
-	emby_auth_header := "MediaBrowser Client=\"Go\", Device=\"Test\", DeviceId=\"Test\", Version=\"1.0.0\""
-	req.Header.Set("X-Emby-Authorization", emby_auth_header)
+	req.Header.Set("X-Emby-Authorization", getAuthorizationHeader(""))
 
 	response, err := ExecuteRequest(req)
 
@@ -106,30 +150,109 @@ func Authorize(baseUrl string, username string, password string) (*AuthResponse,
 		return nil, err
 	}
 
+	return parseAuthResponse(response), nil
+}
+
+// Extracts the token and the user id from the response of an authentication request.
+func parseAuthResponse(response map[string]any) *AuthResponse {
 	accessToken := response["AccessToken"].(string)
 	userId := response["SessionInfo"].(map[string]any)["UserId"].(string)
-	return &AuthResponse{Token: accessToken, UserId: userId}, nil
+	return &AuthResponse{Token: accessToken, UserId: userId}
 }
 
-func MakeRequest(token string, requestUrl string, method string, body any) (map[string]any, error) {
-	http.DefaultTransport.(*http.Transport).TLSClientConfig = &tls.Config{InsecureSkipVerify: true}
-
+// Creates a request against the Jellyfin API which is authenticated with the given token.
+func NewRequest(ctx context.Context, token string, requestUrl string, method string, body any) (*http.Request, error) {
 	// Create Request Body
 	reqbody_json, err := json.Marshal(body)
+	if err != nil {
+		return nil, err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, requestUrl, bytes.NewBuffer(reqbody_json))
+	if err != nil {
+		return nil, err
+	}
 
-	req, err := http.NewRequest(method, requestUrl, bytes.NewBuffer(reqbody_json))
 	req.Header.Set("Content-Type", "application/json")
 
-	// Fix Header by inserting the Authorization header with artificial Values
-	emby_auth_header := "MediaBrowser Client=\"Go\", Device=\"Test\", DeviceId=\"Test\", Version=\"1.0.0\""
-	emby_auth_header += fmt.Sprintf(", Token=\"%s\"", token)
+	if token != "" {
+		// API keys are passed in their own header
+		req.Header.Set("X-Emby-Token", token)
+	}
+
+	req.Header.Set("X-Emby-Authorization", getAuthorizationHeader(token))
+
+	return req, nil
+}
+
+func MakeRequest(ctx context.Context, token string, requestUrl string, method string, body any) (map[string]any, error) {
+	req, err := NewRequest(ctx, token, requestUrl, method, body)
+	if err != nil {
+		return nil, err
+	}
 
-	req.Header.Set("X-Emby-Authorization", emby_auth_header)
+	return ExecuteRequest(req)
+}
 
-	result, err := ExecuteRequest(req)
+// Like MakeRequest, but for endpoints which return a JSON list.
+func MakeListRequest(ctx context.Context, token string, requestUrl string, method string, body any) ([]any, error) {
+	req, err := NewRequest(ctx, token, requestUrl, method, body)
 	if err != nil {
 		return nil, err
 	}
 
-	return result, nil
+	return ExecuteListRequest(req)
+}
+
+// Creates an AuthResponse for the given API key. Since API keys do not belong to a user, the user
+// whose library should be used is looked up by the given username. The username can only be
+// omitted if there is a single user on the server.
+func AuthorizeWithApiKey(ctx context.Context, baseUrl string, apiKey string, username string) (*AuthResponse, error) {
+	users, err := MakeListRequest(ctx, apiKey, BuildUrl(baseUrl, "Users", nil), "GET", nil)
+
+	var statusErr *StatusError
+	if errors.As(err, &statusErr) && statusErr.StatusCode == http.StatusUnauthorized {
+		return nil, errors.New("The API key was rejected by the server (Code 401). Make sure the key exists in the Jellyfin dashboard.")
+	} else if err != nil {
+		return nil, err
+	}
+
+	if username == "" && len(users) != 1 {
+		return nil, errors.New(fmt.Sprintf("Found %d users on the server. Pass -username to select the user whose library should be used.", len(users)))
+	}
+
+	for _, user := range users {
+		rawUser := user.(map[string]any)
+		if username == "" || strings.EqualFold(GetStringFromRawItem(rawUser, "Name"), username) {
+			return &AuthResponse{Token: apiKey, UserId: GetStringFromRawItem(rawUser, "Id")}, nil
+		}
+	}
+
+	return nil, errors.New(fmt.Sprintf("No user with the name %s found on the server", username))
+}
+
+// Switches the given authentication to the library of the user with the given id, e.g. to download
+// items which are only visible to that user. Jellyfin only allows this for administrators, other
+// accounts are rejected with 403.
+func UseUserId(ctx context.Context, baseUrl string, auth *AuthResponse, userId string) error {
+	requestUrl := BuildUrl(baseUrl, fmt.Sprintf("Users/%s/Items", userId), url.Values{"Limit": {"1"}})
+	_, err := MakeRequest(ctx, auth.Token, requestUrl, "GET", nil)
+
+	var statusErr *StatusError
+	if errors.As(err, &statusErr) {
+		switch statusErr.StatusCode {
+		case http.StatusForbidden:
+			return errors.New(fmt.Sprintf("The server does not allow to access the library of user %s (Code 403). Using -user-id requires an administrator account or an API key.", userId))
+		case http.StatusBadRequest, http.StatusNotFound:
+			return errors.New(fmt.Sprintf("No user with the id %s found on the server (Code %d)", userId, statusErr.StatusCode))
+		}
+	}
+
+	if err != nil {
+		return errors.New(fmt.Sprintf("Failed to access the library of user %s: %s", userId, err))
+	}
+
+	slog.Debug("Using the library of another user", "user", userId)
+	auth.UserId = userId
+	return nil
 }
@@ -0,0 +1,120 @@
+package jf_requests
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+var languageCodePattern = regexp.MustCompile(`^[a-z]{2,3}$`)
+
+// Parses a comma separated list of two or three letter language codes like "ja,en". The order of
+// the languages is kept, since the first language is the most preferred one.
+func ParseLanguagePreference(value string) ([]string, error) {
+	var languages []string
+	for _, language := range strings.Split(value, ",") {
+		language = strings.ToLower(strings.TrimSpace(language))
+		if language == "" {
+			continue
+		}
+
+		if !languageCodePattern.MatchString(language) {
+			return nil, errors.New(fmt.Sprintf("Invalid language '%s', use two or three letter codes like en or jpn", language))
+		}
+
+		languages = append(languages, language)
+	}
+
+	return languages, nil
+}
+
+// Returns the stream in the given language. If multiple streams match, the default one is preferred.
+func findLanguageStream(streams []MediaStream, language string) *MediaStream {
+	var selected *MediaStream
+	for idx := range streams {
+		if MatchesLanguage(streams[idx].Language, language) && (selected == nil || (!selected.IsDefault && streams[idx].IsDefault)) {
+			selected = &streams[idx]
+		}
+	}
+
+	return selected
+}
+
+// Returns the audio stream in the original language. Jellyfin does not know the original language
+// of an item, therefore a stream titled "Original" is used or otherwise the default stream of the
+// file, which is the original language for most releases.
+func findOriginalStream(streams []MediaStream) *MediaStream {
+	for idx := range streams {
+		if strings.Contains(strings.ToLower(streams[idx].Title), "original") {
+			return &streams[idx]
+		}
+	}
+
+	for idx := range streams {
+		if streams[idx].IsDefault {
+			return &streams[idx]
+		}
+	}
+
+	return nil
+}
+
+// Selects the audio stream of the given source by the language preferences: the first stream in
+// one of the preferred languages, then the original audio, then the first stream. If preferOriginal
+// is set, the original audio comes first. Returns the stream and the reason it was chosen.
+func SelectPreferredAudioStream(source *MediaSource, languages []string, preferOriginal bool) (*MediaStream, string) {
+	streams := source.GetStreams("Audio")
+	if len(streams) == 0 {
+		return nil, ""
+	}
+
+	if preferOriginal {
+		if stream := findOriginalStream(streams); stream != nil {
+			return stream, "original audio"
+		}
+	}
+
+	for _, language := range languages {
+		if stream := findLanguageStream(streams, language); stream != nil {
+			return stream, fmt.Sprintf("preferred language %s", language)
+		}
+	}
+
+	if stream := findOriginalStream(streams); stream != nil {
+		return stream, "original audio, no track matches the preferred languages"
+	}
+
+	return &streams[0], "first available track"
+}
+
+// Selects a single subtitle stream by the language preferences: the first stream in one of the
+// preferred languages, then the default stream, then the first stream. External subtitles are
+// preferred like in SelectSubtitles. Returns the stream keyed by its language and the reason.
+func SelectPreferredSubtitle(source *MediaSource, languages []string) (map[string]MediaStream, string) {
+	for _, language := range languages {
+		if selected := SelectSubtitles(source, []string{language}); len(selected) > 0 {
+			return selected, fmt.Sprintf("preferred language %s", language)
+		}
+	}
+
+	streams := source.GetStreams("Subtitle")
+	if len(streams) == 0 {
+		return nil, ""
+	}
+
+	stream, reason := &streams[0], "first available subtitles"
+	for idx := range streams {
+		if streams[idx].IsDefault {
+			stream, reason = &streams[idx], "default subtitles, none match the preferred languages"
+			break
+		}
+	}
+
+	language := stream.Language
+	if language == "" {
+		language = "und"
+	}
+
+	return map[string]MediaStream{language: *stream}, reason
+}
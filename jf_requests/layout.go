@@ -0,0 +1,80 @@
+package jf_requests
+
+import (
+	"errors"
+	"fmt"
+	"path/filepath"
+)
+
+// Directory layout of downloaded files.
+type Layout string
+
+const (
+	// All files are stored directly in the output directory.
+	LayoutFlat Layout = "flat"
+	// Series Name (Year)/Season 01/Series Name - S01E01.mkv and Movie Name (Year)/Movie Name (Year).mkv
+	LayoutPlex Layout = "plex"
+	// Kodi uses the same folder structure as Plex.
+	LayoutKodi Layout = "kodi"
+)
+
+// Template used for episode filenames of nested layouts if no template was given.
+const nestedLayoutEpisodeTemplate = "{series} - S{season:02d}E{episode:02d}"
+
+func ParseLayout(layout string) (Layout, error) {
+	switch Layout(layout) {
+	case "", LayoutFlat:
+		return LayoutFlat, nil
+	case LayoutPlex, LayoutKodi:
+		return Layout(layout), nil
+	}
+
+	return "", errors.New(fmt.Sprintf("Unknown layout '%s'. Supported layouts are flat, plex and kodi", layout))
+}
+
+// Returns the name including the year in parentheses, e.g. "Firefly (2002)".
+func getNameWithYear(name string, year int) string {
+	if year == 0 {
+		return SanitizeFilename(name)
+	}
+
+	return SanitizeFilename(fmt.Sprintf("%s (%d)", name, year))
+}
+
+// Returns the directory of the given series, relative to the output directory.
+func (layout Layout) GetSeriesDir(series *Series) string {
+	if layout == LayoutFlat || layout == "" {
+		return ""
+	}
+
+	return getNameWithYear(series.Name, series.Year)
+}
+
+// Returns the directory the episodes of the given season are stored in, relative to the output directory.
+func (layout Layout) GetEpisodeDir(series *Series, season *Season) string {
+	if layout == LayoutFlat || layout == "" {
+		return ""
+	}
+
+	return filepath.Join(layout.GetSeriesDir(series), fmt.Sprintf("Season %02d", season.Index))
+}
+
+// Returns the default episode filename template of the layout or nil if the layout has none.
+func (layout Layout) GetEpisodeTemplate() *FilenameTemplate {
+	if layout == LayoutFlat || layout == "" {
+		return nil
+	}
+
+	template, _ := ParseFilenameTemplate(nestedLayoutEpisodeTemplate)
+	return template
+}
+
+// Returns the path of the movie file (without extension), relative to the output directory.
+func (layout Layout) GetMoviePath(movie *Movie) string {
+	if layout == LayoutFlat || layout == "" {
+		return fmt.Sprintf("%s_%s", SanitizeFilename(movie.Name), SanitizeFilename(movie.Name))
+	}
+
+	name := getNameWithYear(movie.Name, movie.Year)
+	return filepath.Join(name, name)
+}
@@ -0,0 +1,112 @@
+package jf_requests
+
+import (
+	"fmt"
+	"os"
+	"text/tabwriter"
+
+	"github.com/fatih/color"
+)
+
+// Returns the given size in a human readable form or "unknown" if the size is not known.
+func formatSize(size int64) string {
+	if size <= 0 {
+		return "unknown"
+	}
+
+	return FormatBytes(size)
+}
+
+// Returns the given runtime in a human readable form or "unknown" if the runtime is not known.
+func formatRunTime(minutes int) string {
+	if minutes <= 0 {
+		return "unknown"
+	}
+
+	return fmt.Sprintf("%d min", minutes)
+}
+
+// Returns the combined size of all episodes of the season.
+func (season *Season) GetSize() int64 {
+	var size int64 = 0
+	for _, episode := range season.Episodes {
+		size += episode.Size
+	}
+
+	return size
+}
+
+// Returns the description of the audio tracks of the primary media source of the item.
+func (item *MediaItem) getAudioTracks() string {
+	if source := item.GetPrimarySource(); source != nil {
+		return FormatAudioStreams(source.GetStreams("Audio"))
+	}
+
+	return ""
+}
+
+// Returns the ids of the media sources of the item.
+func (item *MediaItem) getSourceIds() []string {
+	var ids []string
+	for _, source := range item.MediaSources {
+		ids = append(ids, source.Id)
+	}
+
+	return ids
+}
+
+// Prints the seasons and episodes of the series as a tree, including their ids, sizes, runtimes and audio tracks.
+// In the JSON output mode an item event is emitted for every season and episode instead.
+func (series *Series) PrintTree() {
+	if JsonOutputEnabled() {
+		for _, season := range series.Seasons {
+			EmitItemEvent("Season", season.Id, season.Name, map[string]any{"series": series.Name, "season": season.Index, "size": season.GetSize()})
+			for _, episode := range season.Episodes {
+				EmitItemEvent("Episode", episode.Id, episode.Name, map[string]any{
+					"series": series.Name, "season": season.Index, "episode": episode.Index,
+					"size": episode.Size, "runtime_minutes": episode.Metadata.RunTimeMinutes, "audio": episode.getAudioTracks(),
+					"sources": episode.getSourceIds(),
+				})
+			}
+		}
+
+		return
+	}
+
+	color.Cyan("%s (%s)", getNameWithYear(series.Name, series.Year), series.Id)
+	for _, season := range series.Seasons {
+		color.Green("  %s (%s): %d episodes, %s", season.Name, season.Id, len(season.Episodes), formatSize(season.GetSize()))
+
+		writer := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		for _, episode := range season.Episodes {
+			fmt.Fprintf(writer, "    E%02d\t%s\t%s\t%s\t%s\t%s\n", episode.Index, episode.Name, episode.Id,
+				formatSize(episode.Size), formatRunTime(episode.Metadata.RunTimeMinutes), episode.getAudioTracks())
+
+			// Episodes with multiple files or encodes list them, so one can be chosen with -source-id
+			if len(episode.MediaSources) > 1 {
+				for _, source := range episode.MediaSources {
+					fmt.Fprintf(writer, "    \t└ %s\t%s\t%s\t\t\n", source.Name, source.Id, formatSize(source.Size))
+				}
+			}
+		}
+
+		writer.Flush()
+	}
+}
+
+// Prints the given items including their type and id.
+func PrintItems(items []Item) {
+	writer := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(writer, "TYPE\tNAME\tYEAR\tID")
+
+	for _, item := range items {
+		year := ""
+		if item.Year > 0 {
+			year = fmt.Sprint(item.Year)
+		}
+
+		fmt.Fprintf(writer, "%s\t%s\t%s\t%s\n", item.Type, item.Name, year, item.Id)
+	}
+
+	writer.Flush()
+}
@@ -0,0 +1,60 @@
+package jf_requests
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/url"
+	"strings"
+)
+
+// Query parameters which contain credentials and must not appear in the log.
+var sensitiveParameters = []string{"api_key", "apikey", "secret", "token"}
+
+// Parses a log level like debug, info, warn or error.
+func ParseLogLevel(level string) (slog.Level, error) {
+	var parsed slog.Level
+	if err := parsed.UnmarshalText([]byte(level)); err != nil {
+		return slog.LevelInfo, errors.New(fmt.Sprintf("Invalid log level '%s'. Use debug, info, warn or error", level))
+	}
+
+	return parsed, nil
+}
+
+// Returns the given link with all credentials in the query replaced by *****, so it can be logged.
+func RedactUrl(link string) string {
+	parsed, err := url.Parse(link)
+	if err != nil {
+		return "*****"
+	}
+
+	query := parsed.Query()
+	for key := range query {
+		for _, sensitive := range sensitiveParameters {
+			if strings.EqualFold(key, sensitive) {
+				query.Set(key, "*****")
+			}
+		}
+	}
+
+	parsed.RawQuery = query.Encode()
+	parsed.User = nil
+	return parsed.String()
+}
+
+// Checks if requests against the given path transfer credentials in their body, so neither the
+// request nor the response may be logged.
+func isSensitivePath(path string) bool {
+	return strings.Contains(path, "Authenticate") || strings.Contains(path, "QuickConnect")
+}
+
+// Removes the credentials from the URL contained in errors of the HTTP client, which would
+// otherwise be shown in the error messages.
+func redactError(err error) error {
+	var urlErr *url.Error
+	if errors.As(err, &urlErr) {
+		urlErr.URL = RedactUrl(urlErr.URL)
+	}
+
+	return err
+}
@@ -0,0 +1,112 @@
+package jf_requests
+
+import (
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// A single file of an m3u playlist.
+type M3UEntry struct {
+	Title string
+	// Length in seconds; -1 if unknown.
+	Duration int
+	Path     string
+	episode  *TemplateValues
+}
+
+// Returns the title of the entry for the given job, which contains the season and episode numbers
+// for episodes, e.g. "Firefly S01E02 - The Train Job".
+func getM3UTitle(job DownloadJob) string {
+	if job.Episode == nil {
+		return job.Media.Name
+	}
+
+	return fmt.Sprintf("%s S%02dE%02d - %s", job.Episode.Series, job.Episode.Season, job.Episode.Episode, job.Episode.Title)
+}
+
+// Returns the entries of all media files which were downloaded or already existed. Episodes are
+// sorted by their season and episode numbers, all other files keep the order of the results.
+func GetM3UEntries(results []DownloadResult) []M3UEntry {
+	var entries []M3UEntry
+	for _, result := range results {
+		if result.Job.Media == nil || result.Err != nil {
+			continue
+		}
+
+		duration := -1
+		if result.Job.Media.RunTime > 0 {
+			duration = int(result.Job.Media.RunTime.Seconds())
+		}
+
+		entries = append(entries, M3UEntry{
+			Title:    getM3UTitle(result.Job),
+			Duration: duration,
+			Path:     result.Job.Outfile,
+			episode:  result.Job.Episode,
+		})
+	}
+
+	sort.SliceStable(entries, func(i, j int) bool {
+		if entries[i].episode == nil || entries[j].episode == nil {
+			return false
+		}
+
+		if entries[i].episode.Season != entries[j].episode.Season {
+			return entries[i].episode.Season < entries[j].episode.Season
+		}
+
+		return entries[i].episode.Episode < entries[j].episode.Episode
+	})
+
+	return entries
+}
+
+// Returns the path of the file relative to dir, using forward slashes as most players expect.
+// The extended-length prefix of Windows paths is removed first, since it can not be part of a
+// relative path.
+func getRelativeM3UPath(dir string, path string) string {
+	if strings.HasPrefix(path, `\\?\UNC\`) {
+		path = `\\` + strings.TrimPrefix(path, `\\?\UNC\`)
+	} else {
+		path = strings.TrimPrefix(path, `\\?\`)
+	}
+
+	absoluteDir, dirErr := filepath.Abs(dir)
+	absolutePath, pathErr := filepath.Abs(path)
+	if dirErr != nil || pathErr != nil {
+		return filepath.ToSlash(path)
+	}
+
+	relative, err := filepath.Rel(absoluteDir, absolutePath)
+	if err != nil {
+		return filepath.ToSlash(absolutePath)
+	}
+
+	return filepath.ToSlash(relative)
+}
+
+// Writes an extended m3u playlist named after name into the output directory, which lists all
+// downloaded media files of the results. Returns the path of the playlist or an empty string if
+// nothing was downloaded.
+func WriteM3U(outputDir string, name string, results []DownloadResult) (string, error) {
+	entries := GetM3UEntries(results)
+	if len(entries) == 0 {
+		return "", nil
+	}
+
+	var content strings.Builder
+	content.WriteString("#EXTM3U\n")
+	for _, entry := range entries {
+		fmt.Fprintf(&content, "#EXTINF:%d,%s\n", entry.Duration, entry.Title)
+		content.WriteString(getRelativeM3UPath(outputDir, entry.Path) + "\n")
+	}
+
+	path := GetFilesystemPath(GetOutputPath(outputDir, SanitizeFilename(name)+".m3u"))
+	if err := WriteLocalFile(path, []byte(content.String())); err != nil {
+		return "", err
+	}
+
+	return path, nil
+}
@@ -0,0 +1,323 @@
+package jf_requests
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"slices"
+	"sync"
+	"time"
+)
+
+// Name of the manifest file inside the output directory, if no other path is given.
+const DefaultManifestName = ".jellyfindownloader-manifest.json"
+
+// Status of a file in the manifest.
+type ManifestStatus string
+
+const (
+	ManifestPending ManifestStatus = "pending"
+	ManifestDone    ManifestStatus = "done"
+	ManifestSkipped ManifestStatus = "skipped"
+	ManifestFailed  ManifestStatus = "failed"
+)
+
+// A single planned file of the manifest. The links are stored without the token, it is added
+// again when the manifest is resumed, so an expired token does not prevent resuming.
+type ManifestEntry struct {
+	Name     string
+	Url      string `json:",omitempty"`
+	Outfile  string
+	Size     int64
+	Optional bool   `json:",omitempty"`
+	Content  []byte `json:",omitempty"`
+	Status   ManifestStatus
+	Error    string          `json:",omitempty"`
+	Episode  *TemplateValues `json:",omitempty"`
+	// Size of the partial file which was flushed to disk at the last checkpoint of an unfinished
+	// download. Data behind it may not have survived a crash.
+	Checkpoint int64 `json:",omitempty"`
+}
+
+// What is known about a downloaded file, so later runs can check if it still matches the file on
+// the server. The checksum is only valid as long as the size and modification time of the local
+// file did not change.
+type FileHash struct {
+	Size      int64
+	ModTime   time.Time
+	Algorithm string `json:",omitempty"`
+	Checksum  string `json:",omitempty"`
+	// ETag of the file on the server when it was downloaded.
+	ETag string `json:",omitempty"`
+}
+
+// Record of all files of a run and their status. It is saved after every change, so an
+// interrupted run can be resumed with -resume.
+type Manifest struct {
+	BaseUrl string
+	Created time.Time
+	Entries []ManifestEntry
+	// Hashes of the downloaded files by their path, which are kept across runs.
+	Hashes map[string]FileHash `json:",omitempty"`
+
+	path  string
+	mutex sync.Mutex
+}
+
+// Creates a new empty manifest, which is stored at the given path.
+func NewManifest(path string, baseUrl string) *Manifest {
+	return &Manifest{BaseUrl: baseUrl, Created: time.Now(), path: path}
+}
+
+// Loads the manifest stored at the given path.
+func LoadManifest(path string) (*Manifest, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, errors.New(fmt.Sprintf("Failed to read manifest: %s", err))
+	}
+
+	manifest := Manifest{path: path}
+	if err := json.Unmarshal(content, &manifest); err != nil {
+		return nil, errors.New(fmt.Sprintf("Failed to parse manifest %s: %s", path, err))
+	}
+
+	return &manifest, nil
+}
+
+// Returns the path of the manifest inside the given output directory.
+func GetDefaultManifestPath(outputDir string) string {
+	return GetOutputPath(outputDir, DefaultManifestName)
+}
+
+// Removes the api_key parameter from the given link.
+func removeToken(link string) string {
+	parsed, err := url.Parse(link)
+	if err != nil {
+		return link
+	}
+
+	query := parsed.Query()
+	query.Del("api_key")
+	parsed.RawQuery = query.Encode()
+	return parsed.String()
+}
+
+// Adds the given token as api_key parameter to the given link.
+func addToken(link string, token string) string {
+	parsed, err := url.Parse(link)
+	if err != nil || link == "" {
+		return link
+	}
+
+	query := parsed.Query()
+	query.Set("api_key", token)
+	parsed.RawQuery = query.Encode()
+	return parsed.String()
+}
+
+// Adds the given jobs to the manifest as pending. Jobs with an output file which is already part
+// of the manifest replace the existing entry.
+func (manifest *Manifest) Add(jobs []DownloadJob) error {
+	manifest.mutex.Lock()
+	defer manifest.mutex.Unlock()
+
+	for _, job := range jobs {
+		entry := ManifestEntry{
+			Name:     job.Name,
+			Url:      removeToken(job.Url),
+			Outfile:  job.Outfile,
+			Size:     job.Size,
+			Optional: job.Optional,
+			Content:  job.Content,
+			Status:   ManifestPending,
+			Episode:  job.Episode,
+		}
+
+		// The checkpoint belongs to the partial file, which is continued when the run is resumed
+		if idx := manifest.indexOf(job.Outfile); idx >= 0 {
+			entry.Checkpoint = manifest.Entries[idx].Checkpoint
+			manifest.Entries[idx] = entry
+		} else {
+			manifest.Entries = append(manifest.Entries, entry)
+		}
+	}
+
+	return manifest.save()
+}
+
+// Stores the outcome of the given download in the manifest.
+func (manifest *Manifest) Update(result DownloadResult) error {
+	manifest.mutex.Lock()
+	defer manifest.mutex.Unlock()
+
+	idx := manifest.indexOf(result.Job.Outfile)
+	if idx < 0 {
+		return nil
+	}
+
+	entry := &manifest.Entries[idx]
+	entry.Status = result.Status()
+	entry.Error = ""
+	if result.Err != nil {
+		entry.Error = result.Err.Error()
+	} else {
+		entry.Checkpoint = 0
+	}
+
+	return manifest.save()
+}
+
+// Records the size of the partial file of the given output file which is known to be on disk.
+func (manifest *Manifest) SetCheckpoint(outfile string, offset int64) error {
+	manifest.mutex.Lock()
+	defer manifest.mutex.Unlock()
+
+	idx := manifest.indexOf(outfile)
+	if idx < 0 || manifest.Entries[idx].Checkpoint == offset {
+		return nil
+	}
+
+	manifest.Entries[idx].Checkpoint = offset
+	return manifest.save()
+}
+
+// Returns the size of the partial file of the given output file at its last checkpoint. Returns
+// false if no checkpoint was recorded.
+func (manifest *Manifest) GetCheckpoint(outfile string) (int64, bool) {
+	manifest.mutex.Lock()
+	defer manifest.mutex.Unlock()
+
+	idx := manifest.indexOf(outfile)
+	if idx < 0 || manifest.Entries[idx].Checkpoint <= 0 {
+		return 0, false
+	}
+
+	return manifest.Entries[idx].Checkpoint, true
+}
+
+// Takes over the hashes of the files of a previous manifest, so they do not need to be computed again.
+func (manifest *Manifest) KeepHashes(previous *Manifest) {
+	manifest.mutex.Lock()
+	defer manifest.mutex.Unlock()
+
+	if manifest.Hashes == nil && len(previous.Hashes) > 0 {
+		manifest.Hashes = make(map[string]FileHash)
+	}
+
+	for path, hash := range previous.Hashes {
+		manifest.Hashes[path] = hash
+	}
+}
+
+// Returns the hash stored for the file at the given path. Returns false if there is none or the
+// file was changed since the hash was stored.
+func (manifest *Manifest) GetHash(path string) (FileHash, bool) {
+	manifest.mutex.Lock()
+	defer manifest.mutex.Unlock()
+
+	hash, ok := manifest.Hashes[path]
+	if !ok {
+		return FileHash{}, false
+	}
+
+	info, err := os.Stat(path)
+	if err != nil || info.Size() != hash.Size || !info.ModTime().Equal(hash.ModTime) {
+		return FileHash{}, false
+	}
+
+	return hash, true
+}
+
+// Stores the hash of the file at the given path. The size and modification time are taken from the file.
+func (manifest *Manifest) SetHash(path string, hash FileHash) error {
+	info, err := os.Stat(path)
+	if err != nil {
+		return err
+	}
+
+	manifest.mutex.Lock()
+	defer manifest.mutex.Unlock()
+
+	hash.Size = info.Size()
+	hash.ModTime = info.ModTime()
+	if manifest.Hashes == nil {
+		manifest.Hashes = make(map[string]FileHash)
+	}
+
+	manifest.Hashes[path] = hash
+	return manifest.save()
+}
+
+// Returns the path the manifest is written to.
+func (manifest *Manifest) Path() string {
+	return manifest.path
+}
+
+// Returns the jobs of all entries which are pending or failed. The given token is used for the download links.
+func (manifest *Manifest) GetUnfinishedJobs(token string) []DownloadJob {
+	return manifest.getJobs(token, ManifestPending, ManifestFailed)
+}
+
+// Returns the jobs of all entries which failed. Unlike GetUnfinishedJobs, files which were never
+// started because the run was interrupted are left out.
+func (manifest *Manifest) GetFailedJobs(token string) []DownloadJob {
+	return manifest.getJobs(token, ManifestFailed)
+}
+
+// Returns the jobs of all entries with one of the given statuses.
+func (manifest *Manifest) getJobs(token string, statuses ...ManifestStatus) []DownloadJob {
+	manifest.mutex.Lock()
+	defer manifest.mutex.Unlock()
+
+	var jobs []DownloadJob
+	for _, entry := range manifest.Entries {
+		if !slices.Contains(statuses, entry.Status) {
+			continue
+		}
+
+		jobs = append(jobs, DownloadJob{
+			Name:     entry.Name,
+			Url:      addToken(entry.Url, token),
+			Outfile:  entry.Outfile,
+			Size:     entry.Size,
+			Optional: entry.Optional,
+			Content:  entry.Content,
+			Episode:  entry.Episode,
+		})
+	}
+
+	return jobs
+}
+
+func (manifest *Manifest) indexOf(outfile string) int {
+	for idx, entry := range manifest.Entries {
+		if entry.Outfile == outfile {
+			return idx
+		}
+	}
+
+	return -1
+}
+
+// Writes the manifest to its path. The file is replaced atomically, so an interruption never
+// leaves a truncated manifest behind.
+func (manifest *Manifest) save() error {
+	content, err := json.MarshalIndent(manifest, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(manifest.path), 0755); err != nil {
+		return errors.New(fmt.Sprintf("Failed to create directory: %s", err))
+	}
+
+	tmpfile := manifest.path + ".tmp"
+	if err := os.WriteFile(tmpfile, content, 0600); err != nil {
+		return errors.New(fmt.Sprintf("Failed to write manifest: %s", err))
+	}
+
+	return os.Rename(tmpfile, manifest.path)
+}
@@ -0,0 +1,56 @@
+package jf_requests
+
+import (
+	"context"
+	"fmt"
+)
+
+// Descriptive metadata of an item.
+type Metadata struct {
+	Overview string
+	// Premiere date in the format YYYY-MM-DD
+	PremiereDate   string
+	RunTimeMinutes int
+	Genres         []string
+}
+
+// Number of RunTimeTicks per minute. Jellyfin counts in ticks of 100 nanoseconds.
+const ticksPerMinute = 600_000_000
+
+// Parses the metadata of the given raw item.
+func GetMetadataFromRawItem(rawItem map[string]any) Metadata {
+	metadata := Metadata{
+		Overview: GetStringFromRawItem(rawItem, "Overview"),
+	}
+
+	// Dates are returned like 2002-09-20T00:00:00.0000000Z
+	if premiere := GetStringFromRawItem(rawItem, "PremiereDate"); len(premiere) >= 10 {
+		metadata.PremiereDate = premiere[:10]
+	}
+
+	if ticks, ok := rawItem["RunTimeTicks"].(float64); ok {
+		metadata.RunTimeMinutes = int(ticks / ticksPerMinute)
+	}
+
+	genres, _ := rawItem["Genres"].([]any)
+	for _, genre := range genres {
+		if name, ok := genre.(string); ok {
+			metadata.Genres = append(metadata.Genres, name)
+		}
+	}
+
+	return metadata
+}
+
+// Fetches the metadata of the item with the given id.
+func GetItemMetadata(ctx context.Context, auth *AuthResponse, baseurl string, id string) (*Metadata, error) {
+	requestUrl := BuildUrl(baseurl, fmt.Sprintf("Users/%s/Items/%s", auth.UserId, id), nil)
+
+	res, err := MakeRequest(ctx, auth.Token, requestUrl, "GET", nil)
+	if err != nil {
+		return nil, err
+	}
+
+	metadata := GetMetadataFromRawItem(res)
+	return &metadata, nil
+}
@@ -0,0 +1,14 @@
+//go:build unix
+
+package jf_requests
+
+import (
+	"errors"
+
+	"golang.org/x/sys/unix"
+)
+
+// Returns true if the file could not be renamed because the target is on another filesystem.
+func isCrossDeviceError(err error) bool {
+	return errors.Is(err, unix.EXDEV)
+}
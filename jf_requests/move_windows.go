@@ -0,0 +1,14 @@
+//go:build windows
+
+package jf_requests
+
+import (
+	"errors"
+
+	"golang.org/x/sys/windows"
+)
+
+// Returns true if the file could not be renamed because the target is on another drive.
+func isCrossDeviceError(err error) bool {
+	return errors.Is(err, windows.ERROR_NOT_SAME_DEVICE)
+}
@@ -0,0 +1,160 @@
+package jf_requests
+
+import (
+	"encoding/xml"
+	"fmt"
+	"path/filepath"
+)
+
+type tvShowNfo struct {
+	XMLName   xml.Name `xml:"tvshow"`
+	Title     string   `xml:"title"`
+	Plot      string   `xml:"plot,omitempty"`
+	Premiered string   `xml:"premiered,omitempty"`
+	Year      int      `xml:"year,omitempty"`
+	Genres    []string `xml:"genre"`
+}
+
+type episodeNfo struct {
+	XMLName   xml.Name `xml:"episodedetails"`
+	Title     string   `xml:"title"`
+	ShowTitle string   `xml:"showtitle"`
+	Season    int      `xml:"season"`
+	Episode   int      `xml:"episode"`
+	Plot      string   `xml:"plot,omitempty"`
+	Aired     string   `xml:"aired,omitempty"`
+	Runtime   int      `xml:"runtime,omitempty"`
+	Genres    []string `xml:"genre"`
+}
+
+type movieNfo struct {
+	XMLName   xml.Name     `xml:"movie"`
+	Title     string       `xml:"title"`
+	Plot      string       `xml:"plot,omitempty"`
+	Premiered string       `xml:"premiered,omitempty"`
+	Year      int          `xml:"year,omitempty"`
+	Runtime   int          `xml:"runtime,omitempty"`
+	Genres    []string     `xml:"genre"`
+	Set       *movieSetNfo `xml:"set,omitempty"`
+}
+
+type movieSetNfo struct {
+	Name string `xml:"name"`
+}
+
+type collectionNfo struct {
+	XMLName xml.Name              `xml:"collection"`
+	Title   string                `xml:"title"`
+	Plot    string                `xml:"plot,omitempty"`
+	Movies  []collectionMemberNfo `xml:"movie"`
+	Series  []collectionMemberNfo `xml:"tvshow"`
+}
+
+type collectionMemberNfo struct {
+	Title string `xml:"title"`
+	Year  int    `xml:"year,omitempty"`
+}
+
+// Serializes the given nfo structure including the XML header. Special characters are escaped.
+func marshalNfo(nfo any) []byte {
+	content, err := xml.MarshalIndent(nfo, "", "  ")
+	if err != nil {
+		// Marshalling only fails for unsupported types, which are not used in the nfo structures
+		panic(err)
+	}
+
+	return append([]byte(xml.Header), append(content, '\n')...)
+}
+
+// Returns the job which writes the tvshow.nfo of the given series.
+func GetSeriesNfoJob(series *Series, opts DownloadOptions) DownloadJob {
+	content := marshalNfo(tvShowNfo{
+		Title:     series.Name,
+		Plot:      series.Metadata.Overview,
+		Premiered: series.Metadata.PremiereDate,
+		Year:      series.Year,
+		Genres:    series.Metadata.Genres,
+	})
+
+	return DownloadJob{
+		Name:    fmt.Sprintf("%s (tvshow.nfo)", series.Name),
+		Outfile: filepath.Join(GetOutputPath(opts.OutputDir, opts.Layout.GetSeriesDir(series)), "tvshow.nfo"),
+		Content: content,
+		Size:    int64(len(content)),
+	}
+}
+
+// Returns the job which writes the nfo file of the given episode next to the episode file.
+// outfile is the path of the episode file without extension.
+func GetEpisodeNfoJob(series *Series, season *Season, episode *Episode, outfile string) DownloadJob {
+	content := marshalNfo(episodeNfo{
+		Title:     episode.Name,
+		ShowTitle: series.Name,
+		Season:    season.Index,
+		Episode:   episode.Index,
+		Plot:      episode.Metadata.Overview,
+		Aired:     episode.Metadata.PremiereDate,
+		Runtime:   episode.Metadata.RunTimeMinutes,
+		Genres:    episode.Metadata.Genres,
+	})
+
+	return DownloadJob{
+		Name:    fmt.Sprintf("%s (nfo)", episode.Name),
+		Outfile: outfile + ".nfo",
+		Content: content,
+		Size:    int64(len(content)),
+	}
+}
+
+// Returns the job which writes the nfo file of the given movie. In the flat layout the file is
+// named after the movie file, otherwise it is stored as movie.nfo in the movie directory.
+func GetMovieNfoJob(movie *Movie, opts DownloadOptions) DownloadJob {
+	var set *movieSetNfo
+	if movie.Set != "" {
+		set = &movieSetNfo{Name: movie.Set}
+	}
+
+	content := marshalNfo(movieNfo{
+		Title:     movie.Name,
+		Plot:      movie.Metadata.Overview,
+		Premiered: movie.Metadata.PremiereDate,
+		Year:      movie.Year,
+		Runtime:   movie.Metadata.RunTimeMinutes,
+		Genres:    movie.Metadata.Genres,
+		Set:       set,
+	})
+
+	outfile := movie.getPreservedOutfile(GetOutputPath(opts.OutputDir, opts.Layout.GetMoviePath(movie)), opts) + ".nfo"
+	if opts.Layout != LayoutFlat && opts.Layout != "" {
+		outfile = filepath.Join(filepath.Dir(outfile), "movie.nfo")
+	}
+
+	return DownloadJob{
+		Name:    fmt.Sprintf("%s (nfo)", movie.Name),
+		Outfile: outfile,
+		Content: content,
+		Size:    int64(len(content)),
+	}
+}
+
+// Returns the job which writes the collection.nfo of the given collection, which lists its movies
+// and series, so media servers can group them again. The file is stored in a directory named after
+// the collection inside the output directory.
+func GetCollectionNfoJob(collection *Collection, opts DownloadOptions) DownloadJob {
+	nfo := collectionNfo{Title: collection.Name, Plot: collection.Metadata.Overview}
+	for _, movie := range collection.Movies {
+		nfo.Movies = append(nfo.Movies, collectionMemberNfo{Title: movie.Name, Year: movie.Year})
+	}
+
+	for _, series := range collection.Series {
+		nfo.Series = append(nfo.Series, collectionMemberNfo{Title: series.Name, Year: series.Year})
+	}
+
+	content := marshalNfo(nfo)
+	return DownloadJob{
+		Name:    fmt.Sprintf("%s (collection.nfo)", collection.Name),
+		Outfile: filepath.Join(GetOutputPath(opts.OutputDir, SanitizeFilename(collection.Name)), "collection.nfo"),
+		Content: content,
+		Size:    int64(len(content)),
+	}
+}
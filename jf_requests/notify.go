@@ -0,0 +1,126 @@
+package jf_requests
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// When the webhook of -notify-url is called.
+type NotifyMode string
+
+const (
+	NotifyAlways NotifyMode = "always"
+	NotifyError  NotifyMode = "error"
+)
+
+// Maximum number of errors which are included in a notification.
+const maxNotifiedErrors = 10
+
+// Maximum time which is spent on delivering the notification.
+const notifyTimeout = 30 * time.Second
+
+func ParseNotifyMode(mode string) (NotifyMode, error) {
+	switch NotifyMode(strings.ToLower(mode)) {
+	case "", NotifyAlways:
+		return NotifyAlways, nil
+	case NotifyError:
+		return NotifyError, nil
+	}
+
+	return "", errors.New(fmt.Sprintf("Unknown value '%s' for -notify-on. Use always or error", mode))
+}
+
+// Checks if the given URL can be used as webhook.
+func ValidateNotifyUrl(notifyUrl string) error {
+	parsed, err := url.Parse(notifyUrl)
+	if err != nil || (parsed.Scheme != "http" && parsed.Scheme != "https") || parsed.Host == "" {
+		return errors.New("Invalid notification URL. Use a URL like https://ntfy.sh/mytopic")
+	}
+
+	return nil
+}
+
+// Returns a single line describing the outcome of the run, e.g.
+// "JellyfinDownloader finished: 10 succeeded, 0 skipped, 0 failed (4.2 GiB in 1h3m0s)".
+func (summary *RunSummary) getNotificationText(elapsed time.Duration, success bool) string {
+	outcome := "finished"
+	if !success {
+		outcome = "failed"
+	}
+
+	return fmt.Sprintf("JellyfinDownloader %s: %d succeeded, %d skipped, %d failed (%s in %s)", outcome, summary.Succeeded,
+		summary.Skipped, summary.Failed, FormatBytes(summary.Bytes), elapsed.Round(time.Second))
+}
+
+// Posts the summary of the run as JSON to the given webhook. Besides the counts, the payload
+// contains the summary as text in the content and text fields, which Discord and Slack show as
+// message. Since the run is already over, a fresh context is used, so the notification is also
+// delivered after a timeout or Ctrl-C.
+func (summary *RunSummary) Notify(notifyUrl string, elapsed time.Duration, success bool) error {
+	text := summary.getNotificationText(elapsed, success)
+
+	errs := []string{}
+	for idx, err := range summary.Errors {
+		if idx == maxNotifiedErrors {
+			errs = append(errs, fmt.Sprintf("and %d more", len(summary.Errors)-maxNotifiedErrors))
+			break
+		}
+
+		errs = append(errs, err.Error())
+	}
+
+	body, err := json.Marshal(map[string]any{
+		"content":   text,
+		"text":      text,
+		"success":   success,
+		"attempted": summary.Attempted(),
+		"succeeded": summary.Succeeded,
+		"skipped":   summary.Skipped,
+		"failed":    summary.Failed,
+		"bytes":     summary.Bytes,
+		"elapsed":   elapsed.Seconds(),
+		"errors":    errs,
+	})
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), notifyTimeout)
+	defer cancel()
+
+	// The request is built by hand, the token of the server must not be sent to the webhook
+	req, err := http.NewRequestWithContext(ctx, "POST", notifyUrl, bytes.NewBuffer(body))
+	if err != nil {
+		return errors.New(fmt.Sprintf("Failed to send the notification: %s", err))
+	}
+
+	req.Header.Set("Content-Type", "application/json")
+
+	res, err := httpClient.Do(req)
+	if err != nil {
+		// Webhook URLs usually contain a secret in their path, so the URL is left out of the error
+		var urlErr *url.Error
+		if errors.As(err, &urlErr) {
+			err = urlErr.Err
+		}
+
+		return errors.New(fmt.Sprintf("Failed to send the notification: %s", err))
+	}
+
+	defer res.Body.Close()
+	io.Copy(io.Discard, io.LimitReader(res.Body, 64*1024))
+
+	if res.StatusCode < 200 || res.StatusCode >= 300 {
+		return errors.New(fmt.Sprintf("Failed to send the notification: the webhook responded with code %d", res.StatusCode))
+	}
+
+	return nil
+}
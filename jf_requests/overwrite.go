@@ -0,0 +1,84 @@
+package jf_requests
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"strings"
+)
+
+// What happens to files which already exist at the output path of a download.
+type OverwritePolicy string
+
+const (
+	// Skip existing files with the size reported by the server and replace all others. Files whose
+	// size is not known in advance, like transcoded streams, are skipped if they exist at all.
+	OverwriteChanged OverwritePolicy = "changed"
+	// Always download the file again and replace the existing one.
+	OverwriteAlways OverwritePolicy = "always"
+	// Never touch an existing file, regardless of its size.
+	OverwriteNever OverwritePolicy = "never"
+)
+
+// How existing files are compared with the server before they are skipped.
+type SkipVerifyMode string
+
+const (
+	// Existing files are skipped if their size matches.
+	SkipVerifySize SkipVerifyMode = "size"
+	// Existing files with the same size are additionally compared by their checksum or ETag.
+	SkipVerifyHash SkipVerifyMode = "hash"
+)
+
+func ParseSkipVerifyMode(mode string) (SkipVerifyMode, error) {
+	switch SkipVerifyMode(strings.ToLower(mode)) {
+	case "", SkipVerifySize:
+		return SkipVerifySize, nil
+	case SkipVerifyHash:
+		return SkipVerifyHash, nil
+	}
+
+	return "", errors.New(fmt.Sprintf("Unknown value '%s' for -skip-verify. Use size or hash", mode))
+}
+
+// Why an existing file is skipped. The reason is one of the fixed values of the skipped event of the
+// JSON output, the message is shown to the user. The zero value means the file is downloaded.
+type existingFileSkip struct {
+	reason  string
+	message string
+}
+
+var (
+	skipExists        = existingFileSkip{reason: "exists", message: "already exists"}
+	skipSameSize      = existingFileSkip{reason: "same-size", message: "already exists with the same size"}
+	skipChecksumMatch = existingFileSkip{reason: "checksum-match", message: "matches the checksum of the server"}
+	skipUnchangedETag = existingFileSkip{reason: "unchanged-etag", message: "is unchanged since it was downloaded"}
+)
+
+// Checks if the download of the given job is skipped because of an existing file. Returns the
+// reason for the skip, or the zero value if the file is downloaded.
+func (policy OverwritePolicy) skipReason(job *DownloadJob) existingFileSkip {
+	switch policy {
+	case OverwriteAlways:
+		return existingFileSkip{}
+	case OverwriteNever:
+		if _, err := os.Stat(job.Outfile); err == nil {
+			return skipExists
+		}
+
+		return existingFileSkip{}
+	}
+
+	if job.IsAlreadyDownloaded() {
+		return skipSameSize
+	}
+
+	// Without a size there is nothing to compare, an empty file is most likely a failed attempt
+	if info, err := os.Stat(job.Outfile); err == nil && job.Size <= 0 && info.Size() > 0 {
+		slog.Debug(fmt.Sprintf("The size of %s is not known in advance, only checked that the file exists", job.Name))
+		return skipExists
+	}
+
+	return existingFileSkip{}
+}
@@ -0,0 +1,107 @@
+package jf_requests
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"sync"
+)
+
+// Maximum number of requests which are sent at once to determine the sizes of the downloads.
+const sizePrefetchConcurrency = 4
+
+// Returns the size of the file behind the given link as announced by the server, or 0 if it is
+// unknown. Servers which do not answer HEAD requests are asked for the first byte instead, the
+// total size is then part of the Content-Range.
+func fetchFileSize(ctx context.Context, link string) int64 {
+	if header, err := fetchFileHeader(ctx, link); err == nil {
+		if size, err := strconv.ParseInt(header.Get("Content-Length"), 10, 64); err == nil && size > 0 {
+			return size
+		}
+	} else {
+		slog.Debug("HEAD request failed, requesting the first byte instead", "url", RedactUrl(link), "error", err)
+	}
+
+	size, err := WithRetry(ctx, fmt.Sprintf("Range request against %s", RedactUrl(link)), func() (int64, error) {
+		return fetchSizeFromRange(ctx, link)
+	})
+
+	if err != nil {
+		slog.Debug("Failed to determine the size from the first byte", "url", RedactUrl(link), "error", err)
+		return 0
+	}
+
+	return size
+}
+
+// Requests the first byte of the file behind the given link and returns the total size of the
+// Content-Range, or 0 if the server does not report it. The request counts towards -api-rate.
+func fetchSizeFromRange(ctx context.Context, link string) (int64, error) {
+	req, err := http.NewRequestWithContext(ctx, "GET", link, nil)
+	if err != nil {
+		return 0, err
+	}
+
+	if err := waitForApiLimit(ctx); err != nil {
+		return 0, err
+	}
+
+	req.Header.Set("Range", "bytes=0-0")
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return 0, &ConnectionError{Err: redactError(err)}
+	}
+
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusPartialContent {
+		return 0, newStatusError(resp, "")
+	}
+
+	return max(GetTotalSizeFromContentRange(resp.Header.Get("Content-Range")), 0), nil
+}
+
+// Determines the sizes of the given items whose size is not part of their metadata, so the total
+// of the confirmation, the disk space check and the download plan are accurate. Up to
+// sizePrefetchConcurrency requests are sent in parallel. Items whose size can not be determined
+// keep their unknown size.
+func PrefetchSizes(ctx context.Context, baseUrl string, token string, items []*MediaItem) {
+	var unknown []*MediaItem
+	for _, item := range items {
+		if item.Size <= 0 {
+			unknown = append(unknown, item)
+		}
+	}
+
+	if len(unknown) == 0 {
+		return
+	}
+
+	slog.Debug(fmt.Sprintf("Determining the sizes of %d files which are not part of the metadata", len(unknown)))
+
+	semaphore := make(chan struct{}, sizePrefetchConcurrency)
+	var wg sync.WaitGroup
+	for _, item := range unknown {
+		wg.Add(1)
+		go func(item *MediaItem) {
+			defer wg.Done()
+			semaphore <- struct{}{}
+			defer func() { <-semaphore }()
+
+			// Alternate versions are downloaded by the id of their media source
+			id := item.Id
+			if item.sourceId != "" {
+				id = item.sourceId
+			}
+
+			// Every worker only writes the size of its own item
+			if size := fetchFileSize(ctx, GetDownloadLinkForId(baseUrl, token, id)); size > 0 {
+				slog.Debug(fmt.Sprintf("Size of %s is %s", item.Name, FormatBytes(size)))
+				item.Size = size
+			}
+		}(item)
+	}
+
+	wg.Wait()
+}
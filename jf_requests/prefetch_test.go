@@ -0,0 +1,54 @@
+package jf_requests
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestFetchFileSizeRetriesTransientErrors(t *testing.T) {
+	content := []byte("0123456789")
+	tests := []struct {
+		name string
+		// Status of the first response to requests of the given method, before the content is served.
+		method string
+		status int
+		// Whether HEAD requests are rejected, so the size is requested with a range.
+		rejectHead bool
+	}{
+		{name: "HEAD request", method: "HEAD", status: http.StatusServiceUnavailable},
+		{name: "range request", method: "GET", status: http.StatusTooManyRequests, rejectHead: true},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var failed atomic.Bool
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if test.rejectHead && r.Method == "HEAD" {
+					w.WriteHeader(http.StatusMethodNotAllowed)
+					return
+				}
+
+				if r.Method == test.method && !failed.Swap(true) {
+					w.WriteHeader(test.status)
+					return
+				}
+
+				http.ServeContent(w, r, "file", time.Time{}, bytes.NewReader(content))
+			}))
+			t.Cleanup(server.Close)
+
+			if size := fetchFileSize(context.Background(), server.URL); size != int64(len(content)) {
+				t.Errorf("fetchFileSize() = %d, want %d", size, len(content))
+			}
+
+			if !failed.Load() {
+				t.Error("the server did not fail the first request")
+			}
+		})
+	}
+}
@@ -0,0 +1,72 @@
+package jf_requests
+
+import (
+	"log/slog"
+	"path/filepath"
+	"strings"
+)
+
+// Splits a path reported by the server into components which can be used below the output
+// directory. Both separators are accepted, since the server may run on Windows. Drive letters,
+// empty components and "." or ".." are dropped, so the path can never leave the output directory.
+func splitServerPath(path string) []string {
+	path = strings.ReplaceAll(path, `\`, "/")
+	if len(path) >= 2 && path[1] == ':' {
+		path = path[2:]
+	}
+
+	var components []string
+	for _, component := range strings.Split(path, "/") {
+		component = SanitizeFilename(component)
+		if component == "" || component == "." || component == ".." {
+			continue
+		}
+
+		components = append(components, component)
+	}
+
+	return components
+}
+
+// Returns the path of the item without extension if PreserveNames is set: the name of the original
+// file on the server, in the directory of outfile or, with MirrorDirs, in the directories of the
+// server below the output directory. Items whose path is unknown keep the given outfile.
+func (item *MediaItem) getPreservedOutfile(outfile string, opts DownloadOptions) string {
+	if !opts.PreserveNames {
+		return outfile
+	}
+
+	source := item.GetPrimarySource()
+	if source == nil || source.Path == "" {
+		slog.Debug("The server reported no path, using the default name", "item", item.Name)
+		return outfile
+	}
+
+	components := splitServerPath(source.Path)
+	if len(components) == 0 {
+		return outfile
+	}
+
+	last := len(components) - 1
+	components[last] = strings.TrimSuffix(components[last], filepath.Ext(components[last]))
+	if components[last] == "" {
+		return outfile
+	}
+
+	relative := components[last]
+	if opts.MirrorDirs {
+		relative = filepath.Join(components...)
+	}
+
+	// Sanitizing the components already prevents this, but the path must never leave the output directory
+	if !filepath.IsLocal(relative) {
+		slog.Warn("Ignoring the path of the server since it leaves the output directory", "item", item.Name, "path", source.Path)
+		return outfile
+	}
+
+	if opts.MirrorDirs {
+		return GetOutputPath(opts.OutputDir, relative)
+	}
+
+	return filepath.Join(filepath.Dir(outfile), relative)
+}
@@ -0,0 +1,110 @@
+package jf_requests
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/fatih/color"
+)
+
+// Returns the bitrate in a human readable form, e.g. "8.5 Mbit/s" or "640 kbit/s".
+func formatBitRate(bitRate int) string {
+	if bitRate <= 0 {
+		return ""
+	} else if bitRate >= 1_000_000 {
+		return fmt.Sprintf("%.1f Mbit/s", float64(bitRate)/1_000_000)
+	}
+
+	return fmt.Sprintf("%d kbit/s", bitRate/1_000)
+}
+
+// Returns the type specific properties of the stream, e.g. the resolution of video streams.
+func getStreamDetails(stream MediaStream) string {
+	var details []string
+	switch stream.Type {
+	case "Video":
+		if stream.Width > 0 && stream.Height > 0 {
+			details = append(details, fmt.Sprintf("%dx%d", stream.Width, stream.Height))
+		}
+	case "Audio":
+		if stream.Channels > 0 {
+			details = append(details, fmt.Sprintf("%d ch", stream.Channels))
+		}
+	}
+
+	if bitRate := formatBitRate(stream.BitRate); bitRate != "" {
+		details = append(details, bitRate)
+	}
+
+	return strings.Join(details, ", ")
+}
+
+// Returns the flags of the stream, e.g. "default, external".
+func getStreamFlags(stream MediaStream) string {
+	var flags []string
+	if stream.IsDefault {
+		flags = append(flags, "default")
+	}
+
+	if stream.IsForced {
+		flags = append(flags, "forced")
+	}
+
+	if stream.IsExternal {
+		flags = append(flags, "external")
+	}
+
+	return strings.Join(flags, ", ")
+}
+
+// Prints the video, audio and subtitle streams of all media sources of the item as table. label
+// is printed as heading, e.g. the name of the item.
+func PrintStreams(label string, item *MediaItem) {
+	if JsonOutputEnabled() {
+		for sourceIdx, source := range item.MediaSources {
+			for _, stream := range source.Streams {
+				EmitEvent("stream", map[string]any{
+					"item": item.Id, "name": label, "source": sourceIdx + 1, "source_id": source.Id, "type": stream.Type, "index": stream.Index,
+					"codec": stream.Codec, "language": stream.Language, "title": stream.Title, "width": stream.Width,
+					"height": stream.Height, "channels": stream.Channels, "bitrate": stream.BitRate,
+					"default": stream.IsDefault, "forced": stream.IsForced, "external": stream.IsExternal,
+				})
+			}
+		}
+
+		return
+	}
+
+	color.Cyan("%s (%s)", label, item.Id)
+	if len(item.MediaSources) == 0 {
+		color.Yellow("  The item has no media file")
+		return
+	}
+
+	for sourceIdx, source := range item.MediaSources {
+		summary := []string{source.Container, formatSize(source.Size)}
+		if bitRate := formatBitRate(source.BitRate); bitRate != "" {
+			summary = append(summary, bitRate)
+		}
+
+		color.Green("  Version %d: %s (%s), source id %s", sourceIdx+1, source.Name, strings.Join(summary, ", "), source.Id)
+
+		writer := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(writer, "    TYPE\tINDEX\tCODEC\tDETAILS\tLANGUAGE\tTITLE\tFLAGS")
+		for _, streamType := range []string{"Video", "Audio", "Subtitle"} {
+			for _, stream := range source.GetStreams(streamType) {
+				language := stream.Language
+				if language == "" {
+					language = "und"
+				}
+
+				fmt.Fprintf(writer, "    %s\t%d\t%s\t%s\t%s\t%s\t%s\n", stream.Type, stream.Index, stream.Codec,
+					getStreamDetails(stream), language, stream.Title, getStreamFlags(stream))
+			}
+		}
+
+		writer.Flush()
+	}
+}
@@ -0,0 +1,102 @@
+package jf_requests
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"time"
+
+	"github.com/schollz/progressbar/v3"
+	"golang.org/x/term"
+)
+
+// Interval in which progress lines are printed when no terminal is attached.
+const plainProgressInterval = 10 * time.Second
+
+// Wraps a reader and reports the progress of the data read from it. When stderr is a terminal, a
+// live progress bar is rendered. Otherwise a plain-text progress line or, in the JSON output mode,
+// a progress event is emitted periodically.
+type ProgressReader struct {
+	reader    io.Reader
+	name      string
+	total     int64
+	read      int64
+	start     time.Time
+	lastPrint time.Time
+	bar       *progressbar.ProgressBar
+}
+
+// Creates a new ProgressReader for the given reader. total is the expected number of bytes or -1
+// if the size is unknown, in which case only the transferred bytes and the speed are shown.
+func NewProgressReader(reader io.Reader, name string, total int64) *ProgressReader {
+	pr := &ProgressReader{
+		reader: reader,
+		name:   name,
+		total:  total,
+		start:  time.Now(),
+	}
+
+	if !JsonOutputEnabled() && term.IsTerminal(int(os.Stderr.Fd())) {
+		pr.bar = CreatePBar(total, name)
+	}
+
+	pr.lastPrint = pr.start
+	return pr
+}
+
+func (pr *ProgressReader) Read(p []byte) (int, error) {
+	n, err := pr.reader.Read(p)
+	pr.read += int64(n)
+
+	if pr.bar != nil {
+		pr.bar.Add(n)
+	} else if time.Since(pr.lastPrint) >= plainProgressInterval || (err == io.EOF && pr.read > 0) {
+		pr.lastPrint = time.Now()
+		if JsonOutputEnabled() {
+			EmitEvent("progress", map[string]any{"name": pr.name, "bytes": pr.read, "total": pr.total})
+		} else {
+			fmt.Fprintln(os.Stderr, pr.String())
+		}
+	}
+
+	return n, err
+}
+
+// Returns the current progress as a single line of plain text.
+func (pr *ProgressReader) String() string {
+	elapsed := time.Since(pr.start).Seconds()
+	var speed float64 = 0
+	if elapsed > 0 {
+		speed = float64(pr.read) / elapsed
+	}
+
+	if pr.total <= 0 {
+		return fmt.Sprintf("%s: %s, %s/s", pr.name, FormatBytes(pr.read), FormatBytes(int64(speed)))
+	}
+
+	percent := float64(pr.read) / float64(pr.total) * 100
+	eta := "unknown"
+	if speed > 0 {
+		remaining := time.Duration(float64(pr.total-pr.read)/speed) * time.Second
+		eta = remaining.Round(time.Second).String()
+	}
+
+	return fmt.Sprintf("%s: %s / %s (%.1f%%), %s/s, ETA %s",
+		pr.name, FormatBytes(pr.read), FormatBytes(pr.total), percent, FormatBytes(int64(speed)), eta)
+}
+
+// Formats the given number of bytes in a human readable form, e.g. 1.5 GiB.
+func FormatBytes(bytes int64) string {
+	const unit = 1024
+	if bytes < unit {
+		return fmt.Sprintf("%d B", bytes)
+	}
+
+	div, exp := int64(unit), 0
+	for n := bytes / unit; n >= unit; n /= unit {
+		div *= unit
+		exp++
+	}
+
+	return fmt.Sprintf("%.1f %ciB", float64(bytes)/float64(div), "KMGTPE"[exp])
+}
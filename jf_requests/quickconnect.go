@@ -0,0 +1,66 @@
+package jf_requests
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/fatih/color"
+)
+
+// Interval in which the server is asked whether the Quick Connect code was authorized.
+const quickConnectPollInterval = 5 * time.Second
+
+// Authorizes against the server using Quick Connect. A code is printed which must be entered
+// in the Quick Connect settings of an already logged in Jellyfin client. Fails if the code
+// was not authorized within the given timeout.
+func AuthorizeWithQuickConnect(ctx context.Context, baseUrl string, timeout time.Duration) (*AuthResponse, error) {
+	initiated, err := MakeRequest(ctx, "", BuildUrl(baseUrl, "QuickConnect/Initiate", nil), "POST", nil)
+
+	var statusErr *StatusError
+	if errors.As(err, &statusErr) && (statusErr.StatusCode == http.StatusUnauthorized || statusErr.StatusCode == http.StatusForbidden) {
+		return nil, errors.New("Quick Connect is disabled on the server. Enable it in the dashboard or log in with username and password.")
+	} else if err != nil {
+		return nil, err
+	}
+
+	secret := GetStringFromRawItem(initiated, "Secret")
+	code := GetStringFromRawItem(initiated, "Code")
+
+	fmt.Println("Enter the following code in the Quick Connect settings of a logged in Jellyfin client:")
+	color.Green("  %s", code)
+
+	deadline := time.Now().Add(timeout)
+	for {
+		state, err := MakeRequest(ctx, "", BuildUrl(baseUrl, "QuickConnect/Connect", url.Values{"Secret": {secret}}), "GET", nil)
+		if err != nil {
+			return nil, err
+		}
+
+		if authenticated, _ := state["Authenticated"].(bool); authenticated {
+			break
+		}
+
+		if time.Now().After(deadline) {
+			return nil, errors.New(fmt.Sprintf("Quick Connect code was not authorized within %s", timeout))
+		}
+
+		slog.Debug("Waiting for Quick Connect authorization")
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-time.After(quickConnectPollInterval):
+		}
+	}
+
+	response, err := MakeRequest(ctx, "", BuildUrl(baseUrl, "Users/AuthenticateWithQuickConnect", nil), "POST", map[string]string{"Secret": secret})
+	if err != nil {
+		return nil, err
+	}
+
+	return parseAuthResponse(response), nil
+}
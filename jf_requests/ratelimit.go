@@ -0,0 +1,128 @@
+package jf_requests
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"golang.org/x/time/rate"
+)
+
+// Limits the combined bandwidth of all downloads. nil means unlimited.
+var downloadLimiter *rate.Limiter
+
+// Limits the number of API requests per second, independently of the bandwidth. nil means unlimited.
+var apiLimiter *rate.Limiter
+
+// Maximum number of bytes which are read at once from a rate limited download.
+const maxRateLimitBurst = 256 * 1024
+
+var ratePattern = regexp.MustCompile(`^(\d+(?:\.\d+)?)\s*([a-zA-Z]*?)(?:/s)?$`)
+
+// Number of bytes per second for each supported unit.
+var rateUnits = map[string]float64{
+	"":     1,
+	"b":    1,
+	"kb":   1_000,
+	"mb":   1_000_000,
+	"gb":   1_000_000_000,
+	"kib":  1024,
+	"mib":  1024 * 1024,
+	"gib":  1024 * 1024 * 1024,
+	"k":    1_000,
+	"m":    1_000_000,
+	"g":    1_000_000_000,
+	"kbit": 1_000 / 8,
+	"mbit": 1_000_000 / 8,
+	"gbit": 1_000_000_000 / 8,
+	"kbps": 1_000 / 8,
+	"mbps": 1_000_000 / 8,
+	"gbps": 1_000_000_000 / 8,
+}
+
+// Parses a transfer rate like 5MB/s, 500KiB/s or 40mbit and returns it in bytes per second.
+// 0 means unlimited.
+func ParseRate(value string) (int64, error) {
+	match := ratePattern.FindStringSubmatch(strings.TrimSpace(value))
+	if match == nil {
+		return 0, errors.New(fmt.Sprintf("Invalid rate '%s'. Use a rate like 5MB/s, 500KiB/s or 40mbit", value))
+	}
+
+	factor, ok := rateUnits[strings.ToLower(match[2])]
+	if !ok {
+		return 0, errors.New(fmt.Sprintf("Unknown unit '%s' in rate '%s'", match[2], value))
+	}
+
+	number, _ := strconv.ParseFloat(match[1], 64)
+	return int64(number * factor), nil
+}
+
+// Limits the combined bandwidth of all downloads to the given number of bytes per second.
+// A limit of 0 disables the limit.
+func SetDownloadLimit(bytesPerSecond int64) {
+	if bytesPerSecond <= 0 {
+		downloadLimiter = nil
+		return
+	}
+
+	burst := int(min(bytesPerSecond, maxRateLimitBurst))
+	downloadLimiter = rate.NewLimiter(rate.Limit(bytesPerSecond), burst)
+}
+
+// Limits the API requests against the server to the given number of requests per second, e.g.
+// 0.5 for a request every two seconds. The downloads themselves are only limited by their
+// bandwidth. A limit of 0 disables the limit.
+func SetApiRateLimit(requestsPerSecond float64) {
+	if requestsPerSecond <= 0 {
+		apiLimiter = nil
+		return
+	}
+
+	// Allow a second worth of requests at once, so short bursts like the start of a run are not slowed down
+	apiLimiter = rate.NewLimiter(rate.Limit(requestsPerSecond), max(int(requestsPerSecond), 1))
+}
+
+// Waits until the next API request may be sent. Returns an error if the context is cancelled first.
+func waitForApiLimit(ctx context.Context) error {
+	if apiLimiter == nil {
+		return nil
+	}
+
+	return apiLimiter.Wait(ctx)
+}
+
+// Reader which waits for the download limiter before passing on the read data.
+type rateLimitedReader struct {
+	ctx     context.Context
+	reader  io.Reader
+	limiter *rate.Limiter
+}
+
+// Wraps the given reader, so it respects the download limit. If no limit is set, the reader is
+// returned as is. Waiting for the limiter is aborted when the context is cancelled.
+func NewRateLimitedReader(ctx context.Context, reader io.Reader) io.Reader {
+	if downloadLimiter == nil {
+		return reader
+	}
+
+	return &rateLimitedReader{ctx: ctx, reader: reader, limiter: downloadLimiter}
+}
+
+func (r *rateLimitedReader) Read(p []byte) (int, error) {
+	if len(p) > r.limiter.Burst() {
+		p = p[:r.limiter.Burst()]
+	}
+
+	n, err := r.reader.Read(p)
+	if n > 0 {
+		if waitErr := r.limiter.WaitN(r.ctx, n); waitErr != nil {
+			return n, waitErr
+		}
+	}
+
+	return n, err
+}
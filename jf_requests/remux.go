@@ -0,0 +1,125 @@
+package jf_requests
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// Codecs which can be stored in a container, and the codecs streams are transcoded to if they
+// can not be copied. nil means that every codec can be stored.
+type containerCodecs struct {
+	Video         []string
+	Audio         []string
+	FallbackVideo string
+	FallbackAudio string
+}
+
+var supportedContainers = map[string]containerCodecs{
+	"mkv": {FallbackVideo: "h264", FallbackAudio: "aac"},
+	"mp4": {
+		Video:         []string{"h264", "hevc", "av1", "vp9", "mpeg4"},
+		Audio:         []string{"aac", "mp3", "ac3", "eac3", "flac", "opus", "alac"},
+		FallbackVideo: "h264",
+		FallbackAudio: "aac",
+	},
+	"mov": {
+		Video:         []string{"h264", "hevc", "mpeg4"},
+		Audio:         []string{"aac", "mp3", "ac3", "eac3", "alac"},
+		FallbackVideo: "h264",
+		FallbackAudio: "aac",
+	},
+	"ts": {
+		Video:         []string{"h264", "hevc", "mpeg2video"},
+		Audio:         []string{"aac", "mp3", "ac3", "eac3"},
+		FallbackVideo: "h264",
+		FallbackAudio: "aac",
+	},
+	"webm": {
+		Video:         []string{"vp8", "vp9", "av1"},
+		Audio:         []string{"opus", "vorbis"},
+		FallbackVideo: "vp9",
+		FallbackAudio: "opus",
+	},
+}
+
+// Parses the name of a container which downloads can be remuxed into, e.g. mp4.
+func ParseContainer(container string) (string, error) {
+	container = strings.ToLower(strings.TrimPrefix(container, "."))
+	if _, ok := supportedContainers[container]; !ok {
+		return "", errors.New(fmt.Sprintf("Unsupported container '%s'. Supported containers are mkv, mov, mp4, ts and webm", container))
+	}
+
+	return container, nil
+}
+
+// Returns the codec the given stream has in the container. If the codec of the stream can be
+// stored in the container, the stream is copied, otherwise it is transcoded to the fallback codec.
+func getContainerCodec(codecs []string, fallback string, stream *MediaStream) string {
+	if stream == nil || stream.Codec == "" {
+		return fallback
+	}
+
+	codec := strings.ToLower(stream.Codec)
+	if codecs == nil || slices.Contains(codecs, codec) {
+		return codec
+	}
+
+	return fallback
+}
+
+// Returns the stream which Jellyfin keeps when only a single stream of the given type is kept.
+func getDefaultStream(source *MediaSource, streamType string) *MediaStream {
+	streams := source.GetStreams(streamType)
+	if len(streams) == 0 {
+		return nil
+	}
+
+	for idx := range streams {
+		if streams[idx].IsDefault {
+			return &streams[idx]
+		}
+	}
+
+	return &streams[0]
+}
+
+// Returns the link to a stream of the given item which is remuxed into the given container. The
+// video and audio streams are copied if the container supports their codecs and only transcoded if
+// it does not. If audioStreamIndex is negative, the default audio stream is kept.
+func GetRemuxLinkForId(baseUrl string, token string, id string, source *MediaSource, container string, audioStreamIndex int) string {
+	codecs := supportedContainers[container]
+
+	var videoStream, audioStream *MediaStream
+	if source != nil {
+		videoStream = getDefaultStream(source, "Video")
+		audioStream = getDefaultStream(source, "Audio")
+		for idx := range source.Streams {
+			if audioStreamIndex >= 0 && source.Streams[idx].Index == audioStreamIndex {
+				audioStream = &source.Streams[idx]
+			}
+		}
+	}
+
+	params := url.Values{}
+	params.Set("static", "false")
+	params.Set("container", container)
+	params.Set("videoCodec", getContainerCodec(codecs.Video, codecs.FallbackVideo, videoStream))
+	params.Set("audioCodec", getContainerCodec(codecs.Audio, codecs.FallbackAudio, audioStream))
+	params.Set("allowVideoStreamCopy", "true")
+	params.Set("allowAudioStreamCopy", "true")
+	params.Set("api_key", token)
+
+	if source != nil && source.Id != "" {
+		params.Set("mediaSourceId", source.Id)
+	}
+
+	if audioStreamIndex >= 0 {
+		params.Set("audioStreamIndex", strconv.Itoa(audioStreamIndex))
+	}
+
+	return BuildUrl(baseUrl, fmt.Sprintf("Videos/%s/stream.%s", id, container), params)
+}
@@ -0,0 +1,250 @@
+package jf_requests
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// A file or directory which is renamed.
+type RenameOperation struct {
+	From string
+	To   string
+}
+
+// A downloaded episode and the values its filename was built from.
+type renameCandidate struct {
+	Path   string
+	Values TemplateValues
+}
+
+// Extensions of the files which are renamed if no manifest exists.
+var videoExtensions = []string{".mkv", ".mp4", ".m4v", ".avi", ".mov", ".ts", ".webm", ".wmv"}
+
+// Matches filenames like "Firefly - S01E02 - The Train Job" or "S1E2 The Train Job".
+var episodeFilenamePattern = regexp.MustCompile(`^(?:(.*?)[\s._-]+)?[Ss](\d+)[\s._]?[Ee](\d+)(?:[\s._-]+(.*))?$`)
+
+var seasonDirPattern = regexp.MustCompile(`^(?i)(season \d+|specials)$`)
+
+var yearSuffixPattern = regexp.MustCompile(`^(.*?)\s*\((\d{4})\)$`)
+
+// Returns the episodes recorded in the manifest which still exist on disk.
+func getManifestCandidates(manifest *Manifest) ([]renameCandidate, []string) {
+	var candidates []renameCandidate
+	var problems []string
+	for _, entry := range manifest.Entries {
+		if entry.Episode == nil || (entry.Status != ManifestDone && entry.Status != ManifestSkipped) {
+			continue
+		}
+
+		if _, err := os.Stat(entry.Outfile); err != nil {
+			problems = append(problems, fmt.Sprintf("Skipping %s: the file does not exist anymore", entry.Outfile))
+			continue
+		}
+
+		candidates = append(candidates, renameCandidate{Path: entry.Outfile, Values: *entry.Episode})
+	}
+
+	return candidates, problems
+}
+
+// Guesses the values of the episode stored at path from its filename. The series and its year are
+// taken from the series directory of nested layouts, if the filename does not contain them.
+func parseEpisodePath(path string) (TemplateValues, bool) {
+	name := strings.TrimSuffix(filepath.Base(path), filepath.Ext(path))
+	match := episodeFilenamePattern.FindStringSubmatch(name)
+	if match == nil {
+		return TemplateValues{}, false
+	}
+
+	values := TemplateValues{Series: match[1], Title: match[4]}
+	values.Season, _ = strconv.Atoi(match[2])
+	values.Episode, _ = strconv.Atoi(match[3])
+
+	if yearMatch := yearSuffixPattern.FindStringSubmatch(values.Series); yearMatch != nil {
+		values.Series = yearMatch[1]
+		values.Year, _ = strconv.Atoi(yearMatch[2])
+	}
+
+	dir := filepath.Dir(path)
+	if !seasonDirPattern.MatchString(filepath.Base(dir)) {
+		return values, true
+	}
+
+	seriesDir := filepath.Base(filepath.Dir(dir))
+	seriesYear := 0
+	if yearMatch := yearSuffixPattern.FindStringSubmatch(seriesDir); yearMatch != nil {
+		seriesDir = yearMatch[1]
+		seriesYear, _ = strconv.Atoi(yearMatch[2])
+	}
+
+	if values.Series == "" && seriesDir != "." && seriesDir != string(filepath.Separator) {
+		values.Series = seriesDir
+	}
+
+	if values.Year == 0 && values.Series == seriesDir {
+		values.Year = seriesYear
+	}
+
+	return values, true
+}
+
+// Searches the given directory for episodes whose values can be parsed from their names.
+func getParsedCandidates(dir string) ([]renameCandidate, []string, error) {
+	var candidates []renameCandidate
+	var problems []string
+	err := filepath.WalkDir(dir, func(path string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+
+		if entry.IsDir() || !slices.Contains(videoExtensions, strings.ToLower(filepath.Ext(path))) {
+			return nil
+		}
+
+		if values, ok := parseEpisodePath(path); ok {
+			candidates = append(candidates, renameCandidate{Path: path, Values: values})
+		} else {
+			problems = append(problems, fmt.Sprintf("Skipping %s: the season and episode can not be determined from the filename", path))
+		}
+
+		return nil
+	})
+
+	if err != nil {
+		return nil, nil, errors.New(fmt.Sprintf("Failed to search %s: %s", dir, err))
+	}
+
+	return candidates, problems, nil
+}
+
+// Returns all files next to the episode at path which belong to it, e.g. subtitles and nfo files.
+// The episode itself is part of the result.
+func getEpisodeFiles(path string) ([]string, error) {
+	basename := strings.TrimSuffix(filepath.Base(path), filepath.Ext(path))
+	entries, err := os.ReadDir(filepath.Dir(path))
+	if err != nil {
+		return nil, err
+	}
+
+	var files []string
+	for _, entry := range entries {
+		if strings.HasPrefix(entry.Name(), basename+".") {
+			files = append(files, filepath.Join(filepath.Dir(path), entry.Name()))
+		}
+	}
+
+	return files, nil
+}
+
+// Plans the renames which are required, so the downloaded episodes in dir match the given template.
+// The metadata of the episodes is taken from the manifest. If manifest is nil, it is parsed from the
+// existing filenames instead. Files which already match the template are left alone, so running
+// the same rename twice does nothing. Returns the operations and the problems of skipped files.
+func PlanRenames(dir string, manifest *Manifest, template *FilenameTemplate) ([]RenameOperation, []string, error) {
+	var candidates []renameCandidate
+	var problems []string
+	if manifest != nil {
+		candidates, problems = getManifestCandidates(manifest)
+	} else {
+		var err error
+		if candidates, problems, err = getParsedCandidates(dir); err != nil {
+			return nil, nil, err
+		}
+	}
+
+	var operations []RenameOperation
+	targets := make(map[string]string)
+	for _, candidate := range candidates {
+		if candidate.Values.Series == "" && template.usesPlaceholder("series") {
+			problems = append(problems, fmt.Sprintf("Skipping %s: the series can not be determined from the filename", candidate.Path))
+			continue
+		}
+
+		oldBase := strings.TrimSuffix(filepath.Base(candidate.Path), filepath.Ext(candidate.Path))
+		newBase := template.Format(candidate.Values)
+		if oldBase == newBase {
+			continue
+		}
+
+		files, err := getEpisodeFiles(candidate.Path)
+		if err != nil {
+			problems = append(problems, fmt.Sprintf("Skipping %s: %s", candidate.Path, err))
+			continue
+		}
+
+		var episodeOperations []RenameOperation
+		var conflict string
+		for _, file := range files {
+			target := filepath.Join(filepath.Dir(file), newBase+strings.TrimPrefix(filepath.Base(file), oldBase))
+			if _, err := os.Lstat(target); err == nil {
+				conflict = fmt.Sprintf("Skipping %s: %s already exists", candidate.Path, target)
+			} else if other, ok := targets[target]; ok {
+				conflict = fmt.Sprintf("Skipping %s: %s would also be renamed to %s", candidate.Path, other, target)
+			}
+
+			episodeOperations = append(episodeOperations, RenameOperation{From: file, To: target})
+		}
+
+		if conflict != "" {
+			problems = append(problems, conflict)
+			continue
+		}
+
+		for _, operation := range episodeOperations {
+			targets[operation.To] = operation.From
+		}
+
+		operations = append(operations, episodeOperations...)
+	}
+
+	return operations, problems, nil
+}
+
+// Executes the given renames and updates the paths in the manifest, if one is given. Failing
+// renames do not stop the remaining ones; their errors are returned.
+func ApplyRenames(operations []RenameOperation, manifest *Manifest) []error {
+	var errs []error
+	for _, operation := range operations {
+		if err := os.Rename(operation.From, operation.To); err != nil {
+			errs = append(errs, errors.New(fmt.Sprintf("Failed to rename %s: %s", operation.From, err)))
+			continue
+		}
+
+		if manifest != nil {
+			manifest.renameOutfile(operation.From, operation.To)
+		}
+	}
+
+	if manifest != nil {
+		manifest.mutex.Lock()
+		defer manifest.mutex.Unlock()
+
+		if err := manifest.save(); err != nil {
+			errs = append(errs, err)
+		}
+	}
+
+	return errs
+}
+
+// Updates the output paths of all entries which are stored at from or inside the directory from.
+func (manifest *Manifest) renameOutfile(from string, to string) {
+	manifest.mutex.Lock()
+	defer manifest.mutex.Unlock()
+
+	for idx := range manifest.Entries {
+		entry := &manifest.Entries[idx]
+		if entry.Outfile == from {
+			entry.Outfile = to
+		} else if strings.HasPrefix(entry.Outfile, from+string(filepath.Separator)) {
+			entry.Outfile = to + strings.TrimPrefix(entry.Outfile, from)
+		}
+	}
+}
@@ -0,0 +1,161 @@
+package jf_requests
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"math/rand/v2"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Maximum number of attempts for requests which failed due to transient errors.
+var MaxAttempts = 3
+
+// Delay before the first retry. Every further retry doubles the delay up to maxRetryDelay.
+const baseRetryDelay = 1 * time.Second
+const maxRetryDelay = 30 * time.Second
+
+// Longest Retry-After of a throttled request which is waited for. Servers asking for longer are
+// retried after this delay anyway, so a single response cannot stall the run indefinitely.
+const maxRetryAfter = 5 * time.Minute
+
+// Error returned when the server responded with an unexpected status code.
+type StatusError struct {
+	StatusCode int
+	Message    string
+	// Delay the server asked for with the Retry-After header, e.g. when it throttles requests; 0 if none.
+	RetryAfter time.Duration
+}
+
+// Creates the error for the given response, including the delay of its Retry-After header.
+func newStatusError(res *http.Response, message string) *StatusError {
+	return &StatusError{StatusCode: res.StatusCode, Message: message, RetryAfter: parseRetryAfter(res.Header.Get("Retry-After"), time.Now())}
+}
+
+// Parses the Retry-After header, which is either a number of seconds or an HTTP date. Returns 0
+// if the header is missing, invalid or lies in the past.
+func parseRetryAfter(value string, now time.Time) time.Duration {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return 0
+	}
+
+	if seconds, err := strconv.Atoi(value); err == nil {
+		return max(time.Duration(seconds)*time.Second, 0)
+	}
+
+	if date, err := http.ParseTime(value); err == nil {
+		return max(date.Sub(now), 0)
+	}
+
+	return 0
+}
+
+func (err *StatusError) Error() string {
+	if err.Message == "" {
+		return fmt.Sprintf("Request Failed (Code %d)", err.StatusCode)
+	}
+
+	return fmt.Sprintf("Request Failed (Code %d): %s", err.StatusCode, err.Message)
+}
+
+// Error returned when the request could not be sent or the response could not be received.
+type ConnectionError struct {
+	Err error
+}
+
+func (err *ConnectionError) Error() string {
+	return fmt.Sprintf("Request Failed: %s", err.Err)
+}
+
+func (err *ConnectionError) Unwrap() error {
+	return err.Err
+}
+
+// Checks if the given status code is transient: server errors and throttled requests (429).
+func isRetriableStatus(statusCode int) bool {
+	return statusCode >= 500 || statusCode == http.StatusTooManyRequests
+}
+
+// Checks if the given error is transient, so the failed operation can be retried.
+// Connection errors, 5xx and 429 responses are retriable, everything else is not. Cancelled
+// operations are never retried.
+func IsRetriable(err error) bool {
+	if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return false
+	}
+
+	var statusErr *StatusError
+	if errors.As(err, &statusErr) {
+		return isRetriableStatus(statusErr.StatusCode)
+	}
+
+	var connErr *ConnectionError
+	return errors.As(err, &connErr)
+}
+
+// Checks if the download of a file which failed with the given error is worth trying again as a
+// whole, e.g. after a failed verification or once the retries of IsRetriable are exhausted.
+// Cancelled downloads, rejected requests and expired sessions would fail the same way again.
+func IsFileRetriable(err error) bool {
+	if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) || errors.Is(err, ErrSessionExpired) {
+		return false
+	}
+
+	var statusErr *StatusError
+	if errors.As(err, &statusErr) {
+		return isRetriableStatus(statusErr.StatusCode)
+	}
+
+	return true
+}
+
+// Returns the delay before the given retry attempt (starting with 1) using exponential backoff with jitter.
+func GetRetryDelay(attempt int) time.Duration {
+	delay := baseRetryDelay << (attempt - 1)
+	if delay <= 0 || delay > maxRetryDelay {
+		delay = maxRetryDelay
+	}
+
+	jitter := time.Duration(rand.Int64N(int64(delay) / 2))
+	return delay/2 + jitter
+}
+
+// Returns the delay before retrying an operation which failed with the given error. If the server
+// asked for a delay with Retry-After, it is honored instead of the exponential backoff.
+func getRetryDelayFor(err error, attempt int) time.Duration {
+	var statusErr *StatusError
+	if errors.As(err, &statusErr) && statusErr.RetryAfter > 0 {
+		return min(statusErr.RetryAfter, maxRetryAfter)
+	}
+
+	return GetRetryDelay(attempt)
+}
+
+// Executes the given function and retries it up to MaxAttempts times as long as it fails with a
+// retriable error. Waiting for the next attempt is aborted when the context is cancelled.
+func WithRetry[T any](ctx context.Context, description string, fn func() (T, error)) (T, error) {
+	var result T
+	var err error
+
+	for attempt := 1; attempt <= max(MaxAttempts, 1); attempt += 1 {
+		result, err = fn()
+		if err == nil || !IsRetriable(err) || attempt >= MaxAttempts {
+			break
+		}
+
+		delay := getRetryDelayFor(err, attempt)
+		slog.Warn(fmt.Sprintf("%s failed, retrying in %s", description, delay.Round(time.Millisecond)), "attempt", attempt, "error", err)
+		select {
+		case <-ctx.Done():
+			return result, ctx.Err()
+		case <-time.After(delay):
+		}
+	}
+
+	return result, err
+}
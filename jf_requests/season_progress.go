@@ -0,0 +1,247 @@
+package jf_requests
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/fatih/color"
+	"golang.org/x/term"
+)
+
+// Interval in which the progress view of the seasons is redrawn on a terminal.
+const seasonViewInterval = 500 * time.Millisecond
+
+// Progress of a season whose episodes are downloaded as a job of their own.
+type seasonProgress struct {
+	name     string
+	episodes int
+	// Combined size of the episodes, without sidecar files.
+	size int64
+	// Updated by the downloads of the season.
+	bytes    atomic.Int64
+	finished atomic.Int64
+	failed   atomic.Int64
+
+	mutex   sync.Mutex
+	started time.Time
+	active  bool
+}
+
+// Counts the outcome of the given result if it belongs to an episode of the season.
+func (progress *seasonProgress) addResult(result DownloadResult) {
+	if result.Job.Episode == nil {
+		return
+	}
+
+	if result.Err != nil {
+		progress.failed.Add(1)
+	} else {
+		progress.finished.Add(1)
+	}
+}
+
+func (progress *seasonProgress) setActive(active bool) {
+	progress.mutex.Lock()
+	defer progress.mutex.Unlock()
+
+	if active {
+		progress.started = time.Now()
+	}
+
+	progress.active = active
+}
+
+func (progress *seasonProgress) isActive() bool {
+	progress.mutex.Lock()
+	defer progress.mutex.Unlock()
+
+	return progress.active
+}
+
+// Returns the progress as a single line, e.g.
+// "Season 2: 3/10 episodes, 1.2 GiB / 4.0 GiB (30%), 11.2 MiB/s".
+func (progress *seasonProgress) String() string {
+	progress.mutex.Lock()
+	elapsed := time.Since(progress.started).Seconds()
+	progress.mutex.Unlock()
+
+	bytes := progress.bytes.Load()
+	line := fmt.Sprintf("%s: %d/%d episodes", progress.name, progress.finished.Load()+progress.failed.Load(), progress.episodes)
+	if failed := progress.failed.Load(); failed > 0 {
+		line += fmt.Sprintf(" (%d failed)", failed)
+	}
+
+	if progress.size > 0 {
+		line += fmt.Sprintf(", %s / %s (%.0f%%)", FormatBytes(bytes), FormatBytes(progress.size), min(float64(bytes)/float64(progress.size)*100, 100))
+	} else {
+		line += fmt.Sprintf(", %s", FormatBytes(bytes))
+	}
+
+	if elapsed > 0 {
+		line += fmt.Sprintf(", %s/s", FormatBytes(int64(float64(bytes)/elapsed)))
+	}
+
+	return line
+}
+
+// Counts the bytes of an episode which were read towards the progress of its season.
+type seasonProgressReader struct {
+	reader   io.Reader
+	progress *seasonProgress
+}
+
+func (reader *seasonProgressReader) Read(p []byte) (int, error) {
+	n, err := reader.reader.Read(p)
+	reader.progress.bytes.Add(int64(n))
+	return n, err
+}
+
+// Shows one line per active season while the seasons are downloaded in parallel. On a terminal
+// the lines are redrawn in place, otherwise they are printed periodically to stderr or emitted as
+// season_progress events in the JSON output mode.
+type seasonView struct {
+	seasons  []*seasonProgress
+	terminal bool
+	// Number of lines which are currently drawn, guarded by outputMutex.
+	lines int
+	stop  chan struct{}
+	done  chan struct{}
+}
+
+// The view which is currently shown, guarded by outputMutex. Messages of the downloads remove the
+// view before they are printed, so they do not mix with its lines.
+var activeSeasonView *seasonView
+
+// Starts rendering the progress of the given seasons until Stop is called.
+func startSeasonView(seasons []*seasonProgress) *seasonView {
+	view := &seasonView{
+		seasons:  seasons,
+		terminal: !JsonOutputEnabled() && term.IsTerminal(int(os.Stderr.Fd())),
+		stop:     make(chan struct{}),
+		done:     make(chan struct{}),
+	}
+
+	interval := plainProgressInterval
+	if view.terminal {
+		interval = seasonViewInterval
+	}
+
+	outputMutex.Lock()
+	activeSeasonView = view
+	outputMutex.Unlock()
+
+	go func() {
+		defer close(view.done)
+
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-view.stop:
+				return
+			case <-ticker.C:
+				outputMutex.Lock()
+				view.render()
+				outputMutex.Unlock()
+			}
+		}
+	}()
+
+	return view
+}
+
+// Removes the lines of the view from the terminal. Must be called with outputMutex held.
+func (view *seasonView) clear() {
+	if view.terminal && view.lines > 0 {
+		fmt.Fprintf(os.Stderr, "\033[%dA\033[J", view.lines)
+	}
+
+	view.lines = 0
+}
+
+// Draws the lines of all active seasons. Must be called with outputMutex held.
+func (view *seasonView) render() {
+	view.clear()
+	for _, progress := range view.seasons {
+		if !progress.isActive() {
+			continue
+		}
+
+		if JsonOutputEnabled() {
+			EmitEvent("season_progress", map[string]any{
+				"season": progress.name, "episodes": progress.episodes, "finished": progress.finished.Load(),
+				"failed": progress.failed.Load(), "bytes": progress.bytes.Load(), "total": progress.size,
+			})
+		} else {
+			fmt.Fprintln(os.Stderr, progress.String())
+		}
+
+		if view.terminal {
+			view.lines++
+		}
+	}
+}
+
+// Finishes the line of the given season by printing its final state above the view.
+func (view *seasonView) finish(progress *seasonProgress) {
+	progress.setActive(false)
+	printMessage(func() {
+		if progress.failed.Load() > 0 {
+			color.Red("Finished %s", progress)
+		} else {
+			color.Green("Finished %s", progress)
+		}
+	})
+}
+
+// Stops rendering and removes the view.
+func (view *seasonView) Stop() {
+	close(view.stop)
+	<-view.done
+
+	outputMutex.Lock()
+	defer outputMutex.Unlock()
+
+	view.clear()
+	activeSeasonView = nil
+}
+
+// Prints a message of the downloads. A progress view of the seasons is removed first and drawn
+// again below the message on its next update.
+func printMessage(print func()) {
+	outputMutex.Lock()
+	defer outputMutex.Unlock()
+
+	if activeSeasonView != nil {
+		activeSeasonView.clear()
+	}
+
+	print()
+}
+
+// Writes to the given writer after removing the progress view of the seasons, so log messages do
+// not mix with its lines.
+type messageWriter struct {
+	writer io.Writer
+}
+
+// Returns a writer for log messages which are printed while downloads are running.
+func NewMessageWriter(writer io.Writer) io.Writer {
+	return &messageWriter{writer: writer}
+}
+
+func (writer *messageWriter) Write(p []byte) (int, error) {
+	outputMutex.Lock()
+	defer outputMutex.Unlock()
+
+	if activeSeasonView != nil {
+		activeSeasonView.clear()
+	}
+
+	return writer.writer.Write(p)
+}
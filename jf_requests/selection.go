@@ -0,0 +1,129 @@
+package jf_requests
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// An episode of a saved selection. Only the id is used to find the episode again, the other
+// fields make the file readable, so it can be reviewed and edited by hand.
+type SelectionEpisode struct {
+	Id      string
+	Name    string
+	Season  int
+	Episode int
+}
+
+// The episodes of a series which were selected.
+type SelectionSeries struct {
+	Id       string
+	Name     string
+	Episodes []SelectionEpisode
+}
+
+// The episodes which were selected interactively, saved with -save-selection. Unlike the
+// manifest, it records what should be downloaded before any download started, and it can be
+// loaded again with -load-selection to download the same episodes without any prompts.
+type Selection struct {
+	BaseUrl string
+	Created time.Time
+	Series  []SelectionSeries
+
+	path string
+}
+
+// Creates a new empty selection, which is stored at the given path.
+func NewSelection(path string, baseUrl string) *Selection {
+	return &Selection{BaseUrl: baseUrl, Created: time.Now(), path: path}
+}
+
+// Loads the selection stored at the given path.
+func LoadSelection(path string) (*Selection, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, errors.New(fmt.Sprintf("Failed to read selection: %s", err))
+	}
+
+	selection := Selection{path: path}
+	if err := json.Unmarshal(content, &selection); err != nil {
+		return nil, errors.New(fmt.Sprintf("Failed to parse selection %s: %s", path, err))
+	}
+
+	if len(selection.Series) == 0 {
+		return nil, errors.New(fmt.Sprintf("The selection %s contains no series", path))
+	}
+
+	return &selection, nil
+}
+
+// Stores the selected episodes of the series in the selection and writes it to its path. A
+// series which is already part of the selection is replaced.
+func (selection *Selection) AddSeries(series *Series, seasons []Season) error {
+	entry := SelectionSeries{Id: series.Id, Name: series.Name}
+	for _, season := range seasons {
+		for _, episode := range season.Episodes {
+			entry.Episodes = append(entry.Episodes, SelectionEpisode{Id: episode.Id, Name: episode.Name, Season: season.Index, Episode: episode.Index})
+		}
+	}
+
+	replaced := false
+	for idx := range selection.Series {
+		if selection.Series[idx].Id == series.Id {
+			selection.Series[idx] = entry
+			replaced = true
+		}
+	}
+
+	if !replaced {
+		selection.Series = append(selection.Series, entry)
+	}
+
+	return selection.save()
+}
+
+// Returns the seasons of the series with only the episodes of the selection. Episodes of the
+// selection which do not exist on the server anymore are returned by their names.
+func (entry *SelectionSeries) FilterSeasons(seasons []Season) ([]Season, []string) {
+	selected := make(map[string]bool)
+	for _, episode := range entry.Episodes {
+		selected[episode.Id] = true
+	}
+
+	found := make(map[string]bool)
+	seasons = FilterEpisodes(seasons, func(season *Season, episode *Episode) bool {
+		found[episode.Id] = selected[episode.Id]
+		return selected[episode.Id]
+	})
+
+	var missing []string
+	for _, episode := range entry.Episodes {
+		if !found[episode.Id] {
+			missing = append(missing, fmt.Sprintf("S%02dE%02d %s", episode.Season, episode.Episode, episode.Name))
+		}
+	}
+
+	return seasons, missing
+}
+
+// Writes the selection to its path. Like the manifest, the file is replaced atomically.
+func (selection *Selection) save() error {
+	content, err := json.MarshalIndent(selection, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(selection.path), 0755); err != nil {
+		return errors.New(fmt.Sprintf("Failed to create directory: %s", err))
+	}
+
+	tmpfile := selection.path + ".tmp"
+	if err := os.WriteFile(tmpfile, content, 0644); err != nil {
+		return errors.New(fmt.Sprintf("Failed to write selection: %s", err))
+	}
+
+	return os.Rename(tmpfile, selection.path)
+}
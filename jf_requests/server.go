@@ -0,0 +1,200 @@
+package jf_requests
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+)
+
+// Version of a Jellyfin server, e.g. 10.9.11.
+type ServerVersion struct {
+	Major int
+	Minor int
+	Patch int
+}
+
+// Oldest and newest minor release of Jellyfin the requests of this tool are known to work with.
+var (
+	minSupportedVersion = ServerVersion{Major: 10, Minor: 8}
+	maxSupportedVersion = ServerVersion{Major: 10, Minor: 11}
+)
+
+// Version of the server detected by CheckServerVersion, nil if it is unknown.
+var detectedVersion struct {
+	mutex   sync.Mutex
+	version *ServerVersion
+}
+
+// Parses a version like "10.9.11". Suffixes of pre-releases like "10.11.0-rc1" are ignored.
+func ParseServerVersion(value string) (ServerVersion, error) {
+	value, _, _ = strings.Cut(strings.TrimPrefix(strings.TrimSpace(value), "v"), "-")
+	parts := strings.Split(value, ".")
+	if len(parts) < 2 || len(parts) > 4 {
+		return ServerVersion{}, errors.New(fmt.Sprintf("Invalid server version '%s'", value))
+	}
+
+	var numbers [3]int
+	for idx := 0; idx < len(parts) && idx < len(numbers); idx++ {
+		number, err := strconv.Atoi(parts[idx])
+		if err != nil || number < 0 {
+			return ServerVersion{}, errors.New(fmt.Sprintf("Invalid server version '%s'", value))
+		}
+
+		numbers[idx] = number
+	}
+
+	return ServerVersion{Major: numbers[0], Minor: numbers[1], Patch: numbers[2]}, nil
+}
+
+func (version ServerVersion) String() string {
+	return fmt.Sprintf("%d.%d.%d", version.Major, version.Minor, version.Patch)
+}
+
+// Checks if the version is the given one or newer, e.g. to use endpoints which were added later.
+func (version ServerVersion) AtLeast(major int, minor int) bool {
+	return version.Major > major || (version.Major == major && version.Minor >= minor)
+}
+
+// Checks if the minor release of the version is in the supported range. Patch releases do not change the API.
+func (version ServerVersion) isSupported() bool {
+	return version.AtLeast(minSupportedVersion.Major, minSupportedVersion.Minor) &&
+		!version.AtLeast(maxSupportedVersion.Major, maxSupportedVersion.Minor+1)
+}
+
+// Returns the version of the server detected by CheckServerVersion or nil if it is unknown.
+func GetServerVersion() *ServerVersion {
+	detectedVersion.mutex.Lock()
+	defer detectedVersion.mutex.Unlock()
+
+	return detectedVersion.version
+}
+
+// Fetches the version of the server from the public system info, which does not require a login,
+// and remembers it for GetServerVersion. Returns a warning if the version is outside the range of
+// supported versions, otherwise an empty string. A version which was already detected by
+// ProbeServer is not fetched again.
+func CheckServerVersion(ctx context.Context, baseUrl string) (string, error) {
+	version := GetServerVersion()
+	if version == nil {
+		res, err := MakeRequest(ctx, "", BuildUrl(baseUrl, "System/Info/Public", nil), "GET", nil)
+		if err != nil {
+			return "", errors.New(fmt.Sprintf("Failed to obtain the version of the server: %s", err))
+		}
+
+		if version, err = setDetectedVersion(res); err != nil {
+			return "", err
+		}
+	}
+
+	if version.isSupported() {
+		return "", nil
+	}
+
+	return fmt.Sprintf("The server runs Jellyfin %s, but only the versions %d.%d to %d.%d are supported. Some requests may fail "+
+		"because the API of the server differs.", version, minSupportedVersion.Major, minSupportedVersion.Minor,
+		maxSupportedVersion.Major, maxSupportedVersion.Minor), nil
+}
+
+// Parses the version of the public system info and remembers it for GetServerVersion.
+func setDetectedVersion(info map[string]any) (*ServerVersion, error) {
+	version, err := ParseServerVersion(GetStringFromRawItem(info, "Version"))
+	if err != nil {
+		return nil, err
+	}
+
+	detectedVersion.mutex.Lock()
+	detectedVersion.version = &version
+	detectedVersion.mutex.Unlock()
+
+	return &version, nil
+}
+
+// Maximum time ProbeServer waits for the answer of the server.
+const serverProbeTimeout = 15 * time.Second
+
+// Checks if the server can be reached at all before any credentials are requested, so a wrong
+// URL or a network problem is reported before the user typed a password. The public system info
+// is requested once, without retries. Returns an error which names the likely cause if no
+// connection could be established, e.g. an unknown host or an untrusted certificate. If the
+// server answers, but not like Jellyfin does, e.g. because the subpath of the URL is missing or
+// the system info is hidden, a warning is returned instead.
+func ProbeServer(ctx context.Context, baseUrl string) (string, error) {
+	ctx, cancel := context.WithTimeout(ctx, serverProbeTimeout)
+	defer cancel()
+
+	req, err := NewRequest(ctx, "", BuildUrl(baseUrl, "System/Info/Public", nil), "GET", nil)
+	if err != nil {
+		return "", err
+	}
+
+	content, err := executeRequestOnce(req)
+	var statusErr *StatusError
+	if errors.As(err, &statusErr) {
+		return fmt.Sprintf("The server at %s answered with status %d instead of the Jellyfin system info. Check that -url "+
+			"points to Jellyfin, including its subpath if it is served under one.", baseUrl, statusErr.StatusCode), nil
+	} else if err != nil {
+		return "", describeConnectionError(ctx, baseUrl, err)
+	}
+
+	var info map[string]any
+	if err := json.Unmarshal(content, &info); err != nil || GetStringFromRawItem(info, "Version") == "" {
+		return fmt.Sprintf("The server at %s does not look like a Jellyfin server. Check that -url points to Jellyfin, "+
+			"including its subpath if it is served under one.", baseUrl), nil
+	}
+
+	if _, err := setDetectedVersion(info); err != nil {
+		slog.Debug(err.Error())
+	}
+
+	return "", nil
+}
+
+// Returns an error which explains why no connection to the server could be established.
+func describeConnectionError(ctx context.Context, baseUrl string, err error) error {
+	host := baseUrl
+	if parsed, parseErr := url.Parse(baseUrl); parseErr == nil {
+		host = parsed.Host
+	}
+
+	var dnsErr *net.DNSError
+	var certErr *tls.CertificateVerificationError
+	var hostnameErr x509.HostnameError
+	var authorityErr x509.UnknownAuthorityError
+	var recordErr tls.RecordHeaderError
+	var urlErr *url.Error
+	var netErr net.Error
+
+	switch {
+	case errors.As(err, &urlErr) && urlErr.Op == "proxyconnect":
+		return errors.New(fmt.Sprintf("The proxy could not be reached: %s. Check -proxy, HTTP_PROXY and HTTPS_PROXY.", urlErr.Err))
+	case errors.As(err, &dnsErr):
+		return errors.New(fmt.Sprintf("The host %s could not be resolved: %s. Check the hostname in -url.", dnsErr.Name, dnsErr.Err))
+	case errors.As(err, &certErr), errors.As(err, &hostnameErr), errors.As(err, &authorityErr):
+		cause := err
+		if certErr != nil {
+			cause = certErr.Err
+		}
+
+		return errors.New(fmt.Sprintf("The TLS certificate of %s is not trusted: %s. Pass the certificate of its CA with -cacert, "+
+			"or -insecure to skip the verification.", host, cause))
+	case errors.As(err, &recordErr) || strings.Contains(err.Error(), "server gave HTTP response to HTTPS client"):
+		return errors.New(fmt.Sprintf("%s does not speak TLS. Check if -url should start with http:// instead of https://.", host))
+	case errors.Is(err, syscall.ECONNREFUSED):
+		return errors.New(fmt.Sprintf("The connection to %s was refused. Check the port in -url and that Jellyfin is running.", host))
+	case errors.Is(ctx.Err(), context.DeadlineExceeded) || (errors.As(err, &netErr) && netErr.Timeout()):
+		return errors.New(fmt.Sprintf("%s did not answer within %s. Check the hostname and port in -url and your network.", host, serverProbeTimeout))
+	}
+
+	return errors.New(fmt.Sprintf("The server at %s could not be reached: %s", baseUrl, err))
+}
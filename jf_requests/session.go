@@ -0,0 +1,127 @@
+package jf_requests
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"sync"
+)
+
+// Error returned once the token was rejected and logging in again failed.
+var ErrSessionExpired = errors.New("The session expired, please re-authenticate")
+
+// Returned by refreshToken if no session was registered, e.g. while logging in.
+var errReauthenticationDisabled = errors.New("Reauthentication is disabled")
+
+// State of the session which is refreshed when its token expires.
+var session struct {
+	mutex          sync.Mutex
+	auth           *AuthResponse
+	reauthenticate func(ctx context.Context) (*AuthResponse, error)
+	expired        error
+}
+
+// Registers the authentication of the current run. If the server rejects its token later on,
+// reauthenticate is used to log in again and the token of auth is replaced. If reauthenticate is
+// nil, e.g. for API keys, a rejected token ends the session right away.
+func EnableReauthentication(auth *AuthResponse, reauthenticate func(ctx context.Context) (*AuthResponse, error)) {
+	session.mutex.Lock()
+	defer session.mutex.Unlock()
+
+	session.auth = auth
+	session.reauthenticate = reauthenticate
+	session.expired = nil
+}
+
+// Returns the error why the session ended or nil if it is still valid.
+func SessionExpired() error {
+	session.mutex.Lock()
+	defer session.mutex.Unlock()
+
+	return session.expired
+}
+
+// Logs in again after the given token was rejected and returns the new token. If the token was
+// already refreshed by another request, the current token is returned without logging in again.
+func refreshToken(ctx context.Context, rejected string) (string, error) {
+	session.mutex.Lock()
+	defer session.mutex.Unlock()
+
+	if session.auth == nil || rejected == "" {
+		return "", errReauthenticationDisabled
+	} else if session.expired != nil {
+		return "", session.expired
+	} else if session.auth.Token != rejected {
+		return session.auth.Token, nil
+	}
+
+	if session.reauthenticate == nil {
+		session.expired = fmt.Errorf("%w: the token was rejected by the server", ErrSessionExpired)
+		return "", session.expired
+	}
+
+	slog.Info("The server rejected the token, logging in again")
+	auth, err := session.reauthenticate(ctx)
+	if err != nil {
+		session.expired = fmt.Errorf("%w: %s", ErrSessionExpired, err)
+		return "", session.expired
+	}
+
+	session.auth.Token = auth.Token
+	return auth.Token, nil
+}
+
+// Executes fn with the given token. If the server rejects the token, the session is refreshed and
+// fn is executed once more with the new token.
+func withReauthentication[T any](ctx context.Context, token string, fn func(token string) (T, error)) (T, error) {
+	result, err := fn(token)
+
+	var statusErr *StatusError
+	if !errors.As(err, &statusErr) || statusErr.StatusCode != http.StatusUnauthorized {
+		return result, err
+	}
+
+	newToken, refreshErr := refreshToken(ctx, token)
+	if errors.Is(refreshErr, errReauthenticationDisabled) {
+		return result, err
+	} else if refreshErr != nil {
+		return result, refreshErr
+	}
+
+	return fn(newToken)
+}
+
+// Returns the token the given request is authenticated with.
+func getRequestToken(request *http.Request) string {
+	if token := request.Header.Get("X-Emby-Token"); token != "" {
+		return token
+	}
+
+	return request.URL.Query().Get("api_key")
+}
+
+// Returns the token contained in the api_key parameter of the given link.
+func getLinkToken(link string) string {
+	parsed, err := url.Parse(link)
+	if err != nil {
+		return ""
+	}
+
+	return parsed.Query().Get("api_key")
+}
+
+// Replaces the token the given request is authenticated with.
+func setRequestToken(request *http.Request, token string) {
+	if request.Header.Get("X-Emby-Token") != "" {
+		request.Header.Set("X-Emby-Token", token)
+	}
+
+	if request.URL.Query().Has("api_key") {
+		request.URL, _ = url.Parse(addToken(request.URL.String(), token))
+	}
+
+	request.Header.Set("X-Emby-Authorization", getAuthorizationHeader(token))
+}
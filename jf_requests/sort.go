@@ -0,0 +1,62 @@
+package jf_requests
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"sort"
+	"strings"
+)
+
+// Order in which the episodes of a series are downloaded.
+type SortOrder string
+
+const (
+	SortAscending      SortOrder = "asc"
+	SortDescending     SortOrder = "desc"
+	SortSizeAscending  SortOrder = "size-asc"
+	SortSizeDescending SortOrder = "size-desc"
+)
+
+func ParseSortOrder(order string) (SortOrder, error) {
+	switch SortOrder(strings.ToLower(order)) {
+	case "", SortAscending:
+		return SortAscending, nil
+	case SortDescending, SortSizeAscending, SortSizeDescending:
+		return SortOrder(strings.ToLower(order)), nil
+	}
+
+	return "", errors.New(fmt.Sprintf("Unknown value '%s' for -sort. Use asc, desc, size-asc or size-desc", order))
+}
+
+// The jobs of a single episode, i.e. the episode itself followed by its sidecar files.
+type episodeJobs struct {
+	size int64
+	jobs []DownloadJob
+}
+
+// Returns the jobs of the episodes in the given order. groups are expected in ascending order of
+// their season and episode number. Only the order of the episodes changes, the sidecar files stay
+// with their episode and the filenames are not affected.
+func sortEpisodeJobs(groups []episodeJobs, order SortOrder) []DownloadJob {
+	groups = slices.Clone(groups)
+	switch order {
+	case SortDescending:
+		slices.Reverse(groups)
+	case SortSizeAscending:
+		sort.SliceStable(groups, func(i, j int) bool {
+			return groups[i].size < groups[j].size
+		})
+	case SortSizeDescending:
+		sort.SliceStable(groups, func(i, j int) bool {
+			return groups[i].size > groups[j].size
+		})
+	}
+
+	var jobs []DownloadJob
+	for _, group := range groups {
+		jobs = append(jobs, group.jobs...)
+	}
+
+	return jobs
+}
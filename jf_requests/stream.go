@@ -0,0 +1,97 @@
+package jf_requests
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"time"
+)
+
+// Writes the media file of the single episode or movie among the given jobs to opts.Stream
+// instead of a file, e.g. to pipe it into a player. Sidecar files like subtitles can not be
+// streamed and are left out. Since the stream can not be rewound, only requests which failed
+// before the first byte was written are retried.
+func streamJobs(ctx context.Context, jobs []DownloadJob, opts DownloadOptions) []DownloadResult {
+	var media []DownloadJob
+	for _, job := range jobs {
+		if job.Content == nil && (job.Media != nil || job.Episode != nil) {
+			media = append(media, job)
+		}
+	}
+
+	if len(media) != 1 {
+		err := errors.New(fmt.Sprintf("-stdout streams a single episode or movie, but %d were selected. Use -seasonid, -episodes or -pick to select a single episode.", len(media)))
+		results := make([]DownloadResult, len(media))
+		for idx, job := range media {
+			results[idx] = DownloadResult{Job: job, Err: err}
+		}
+
+		if len(results) == 0 {
+			results = append(results, DownloadResult{Job: DownloadJob{Name: "-stdout"}, Err: err})
+		}
+
+		return results
+	}
+
+	job := media[0]
+	if job.Err != nil {
+		return []DownloadResult{{Job: job, Err: job.Err}}
+	}
+
+	slog.Info(fmt.Sprintf("Streaming %s to stdout", job.Name))
+
+	var transferred int64
+	_, err := withReauthentication(ctx, getLinkToken(job.Url), func(token string) (any, error) {
+		if token != getLinkToken(job.Url) {
+			job.Url = addToken(job.Url, token)
+		}
+
+		return WithRetry(ctx, fmt.Sprintf("Stream of %s", job.Name), func() (any, error) {
+			written, err := streamFromUrl(ctx, job, opts.Stream)
+			transferred += written
+			return nil, err
+		})
+	})
+
+	if err != nil && ctx.Err() != nil {
+		err = fmt.Errorf("Stream of %s cancelled: %w", job.Name, ctx.Err())
+	}
+
+	return []DownloadResult{{Job: job, Err: err, Bytes: transferred}}
+}
+
+// Copies the response for the link of the job to the given writer and returns the number of
+// bytes which were written.
+func streamFromUrl(ctx context.Context, job DownloadJob, writer io.Writer) (int64, error) {
+	req, err := http.NewRequestWithContext(ctx, "GET", job.Url, nil)
+	if err != nil {
+		return 0, err
+	}
+
+	start := time.Now()
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return 0, &ConnectionError{Err: redactError(err)}
+	}
+
+	defer resp.Body.Close()
+	slog.Debug(fmt.Sprintf("Response from %s", RedactUrl(job.Url)), "status", resp.StatusCode, "duration", time.Since(start))
+
+	if resp.StatusCode != http.StatusOK {
+		return 0, newStatusError(resp, "")
+	}
+
+	reader := NewProgressReader(NewRateLimitedReader(ctx, resp.Body), job.Name, resp.ContentLength)
+	written, err := io.Copy(writer, reader)
+	if err != nil && written > 0 {
+		// The bytes which were already written can not be taken back, so the stream is not retried
+		return written, errors.New(fmt.Sprintf("Stream of %s interrupted after %s: %s", job.Name, FormatBytes(written), err))
+	} else if err != nil {
+		return written, &ConnectionError{Err: err}
+	}
+
+	return written, nil
+}
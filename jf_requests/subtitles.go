@@ -0,0 +1,110 @@
+package jf_requests
+
+import (
+	"fmt"
+	"maps"
+	"net/url"
+	"path/filepath"
+	"slices"
+	"strings"
+)
+
+// Format subtitles are downloaded in.
+const SubtitleFormat = "srt"
+
+// Maps two letter language codes to the three letter codes Jellyfin uses for media streams.
+var languageCodes = map[string][]string{
+	"ar": {"ara"},
+	"cs": {"cze", "ces"},
+	"da": {"dan"},
+	"de": {"ger", "deu"},
+	"el": {"gre", "ell"},
+	"en": {"eng"},
+	"es": {"spa"},
+	"fi": {"fin"},
+	"fr": {"fre", "fra"},
+	"he": {"heb"},
+	"hi": {"hin"},
+	"hu": {"hun"},
+	"it": {"ita"},
+	"ja": {"jpn"},
+	"ko": {"kor"},
+	"nl": {"dut", "nld"},
+	"no": {"nor"},
+	"pl": {"pol"},
+	"pt": {"por"},
+	"ru": {"rus"},
+	"sv": {"swe"},
+	"tr": {"tur"},
+	"uk": {"ukr"},
+	"zh": {"chi", "zho"},
+}
+
+// Checks if the language of a media stream matches the wanted language. The wanted language can
+// be given as two or three letter code.
+func MatchesLanguage(streamLanguage string, wanted string) bool {
+	streamLanguage = strings.ToLower(streamLanguage)
+	wanted = strings.ToLower(wanted)
+
+	if streamLanguage == wanted {
+		return true
+	}
+
+	for _, code := range languageCodes[wanted] {
+		if code == streamLanguage {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Selects one subtitle stream per language. External subtitles are preferred over embedded ones.
+// If languages is not empty, only subtitles in one of the given languages are returned. The
+// returned map contains the language used in the file name for every selected stream.
+func SelectSubtitles(source *MediaSource, languages []string) map[string]MediaStream {
+	selected := make(map[string]MediaStream)
+
+	for _, stream := range source.GetStreams("Subtitle") {
+		language := stream.Language
+		if len(languages) > 0 {
+			language = ""
+			for _, wanted := range languages {
+				if MatchesLanguage(stream.Language, wanted) {
+					language = wanted
+					break
+				}
+			}
+
+			if language == "" {
+				continue
+			}
+		} else if language == "" {
+			language = "und"
+		}
+
+		if current, ok := selected[language]; !ok || (!current.IsExternal && stream.IsExternal) {
+			selected[language] = stream
+		}
+	}
+
+	return selected
+}
+
+// Returns the download jobs for the given subtitles of an item, stored as sidecar files next to videoOutfile.
+func GetSubtitleJobs(baseUrl string, token string, itemId string, name string, source *MediaSource, videoOutfile string, subtitles map[string]MediaStream) []DownloadJob {
+	basename := strings.TrimSuffix(videoOutfile, filepath.Ext(videoOutfile))
+
+	var jobs []DownloadJob
+	for _, language := range slices.Sorted(maps.Keys(subtitles)) {
+		stream := subtitles[language]
+		jobs = append(jobs, DownloadJob{
+			Name: fmt.Sprintf("%s (%s subtitles)", name, language),
+			Url: BuildUrl(baseUrl, fmt.Sprintf("Videos/%s/%s/Subtitles/%d/Stream.%s", itemId, source.Id, stream.Index, SubtitleFormat),
+				url.Values{"api_key": {token}}),
+			Outfile: fmt.Sprintf("%s.%s.%s", basename, language, SubtitleFormat),
+		})
+	}
+
+	return jobs
+}
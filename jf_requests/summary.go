@@ -0,0 +1,104 @@
+package jf_requests
+
+import (
+	"fmt"
+	"os"
+	"text/tabwriter"
+	"time"
+
+	"github.com/fatih/color"
+)
+
+// Aggregated outcome of the downloads of a run.
+type RunSummary struct {
+	Succeeded int
+	Skipped   int
+	Failed    int
+	// Number of bytes which were transferred from the server.
+	Bytes int64
+	// Errors of the failed files and of items which could not be downloaded at all.
+	Errors []error
+	// Episodes which only exist in the metadata of the server, reported with -include-missing.
+	Missing []string
+}
+
+// Returns a summary which only consists of the given error.
+func NewFailedSummary(err error) RunSummary {
+	return RunSummary{Errors: []error{err}}
+}
+
+// Returns the summary of the given download results.
+func SummarizeResults(results []DownloadResult) RunSummary {
+	var summary RunSummary
+	for _, result := range results {
+		summary.Bytes += result.Bytes
+		switch result.Status() {
+		case ManifestFailed:
+			summary.Failed += 1
+			summary.Errors = append(summary.Errors, fmt.Errorf("%s: %w", result.Job.Name, result.Err))
+		case ManifestSkipped:
+			summary.Skipped += 1
+		default:
+			summary.Succeeded += 1
+		}
+	}
+
+	return summary
+}
+
+// Adds the counts and errors of the other summary.
+func (summary *RunSummary) Merge(other RunSummary) {
+	summary.Succeeded += other.Succeeded
+	summary.Skipped += other.Skipped
+	summary.Failed += other.Failed
+	summary.Bytes += other.Bytes
+	summary.Errors = append(summary.Errors, other.Errors...)
+	summary.Missing = append(summary.Missing, other.Missing...)
+}
+
+// Number of files which were attempted to download.
+func (summary *RunSummary) Attempted() int {
+	return summary.Succeeded + summary.Skipped + summary.Failed
+}
+
+// Returns true if nothing failed.
+func (summary *RunSummary) Success() bool {
+	return len(summary.Errors) == 0
+}
+
+// Prints the summary as table, including the elapsed time of the run.
+func (summary *RunSummary) Print(elapsed time.Duration) {
+	EmitEvent("report", map[string]any{
+		"attempted": summary.Attempted(),
+		"succeeded": summary.Succeeded,
+		"skipped":   summary.Skipped,
+		"failed":    summary.Failed,
+		"missing":   len(summary.Missing),
+		"bytes":     summary.Bytes,
+		"elapsed":   elapsed.Seconds(),
+	})
+
+	fmt.Println("Report:")
+	writer := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintf(writer, "  Attempted\t%d\n", summary.Attempted())
+	fmt.Fprintf(writer, "  Succeeded\t%d\n", summary.Succeeded)
+	fmt.Fprintf(writer, "  Skipped\t%d\n", summary.Skipped)
+	fmt.Fprintf(writer, "  Failed\t%d\n", summary.Failed)
+	if len(summary.Missing) > 0 {
+		fmt.Fprintf(writer, "  Missing on server\t%d\n", len(summary.Missing))
+	}
+	fmt.Fprintf(writer, "  Transferred\t%s\n", FormatBytes(summary.Bytes))
+	fmt.Fprintf(writer, "  Elapsed\t%s\n", elapsed.Round(time.Second))
+	writer.Flush()
+
+	for _, name := range summary.Missing {
+		color.Yellow("  - %s: missing on server", name)
+	}
+
+	if !summary.Success() {
+		color.Red("%d errors occurred:", len(summary.Errors))
+		for _, err := range summary.Errors {
+			color.Red("  ✗ %s", err)
+		}
+	}
+}
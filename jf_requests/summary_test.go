@@ -0,0 +1,33 @@
+package jf_requests
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestDownloadResultStatus(t *testing.T) {
+	err := errors.New("failed")
+	results := []DownloadResult{
+		{Job: DownloadJob{Name: "done"}},
+		{Job: DownloadJob{Name: "skipped"}, Skipped: true},
+		{Job: DownloadJob{Name: "failed"}, Err: err},
+		{Job: DownloadJob{Name: "skipped and failed"}, Skipped: true, Err: err},
+	}
+
+	want := []ManifestStatus{ManifestDone, ManifestSkipped, ManifestFailed, ManifestFailed}
+	for idx, result := range results {
+		if got := result.Status(); got != want[idx] {
+			t.Errorf("Status() of %s = %s, want %s", result.Job.Name, got, want[idx])
+		}
+	}
+
+	summary := SummarizeResults(results)
+	if summary.Succeeded != 1 || summary.Skipped != 1 || summary.Failed != 2 || len(summary.Errors) != 2 {
+		t.Errorf("SummarizeResults() = %d succeeded, %d skipped, %d failed with %d errors, want 1, 1, 2 and 2",
+			summary.Succeeded, summary.Skipped, summary.Failed, len(summary.Errors))
+	}
+
+	if PrintDownloadSummary(results) {
+		t.Error("PrintDownloadSummary() reported success, want a failure")
+	}
+}
@@ -0,0 +1,165 @@
+package jf_requests
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"golang.org/x/text/unicode/norm"
+)
+
+// Placeholders which can be used in filename templates. Numeric placeholders support a width
+// specifier like {season:02d}.
+var templatePlaceholders = map[string]bool{
+	"series":  false,
+	"season":  true,
+	"episode": true,
+	"title":   false,
+	"year":    true,
+}
+
+// Largest width which can be set as default padding of the season and episode numbers.
+const MaxTemplatePadding = 9
+
+var templatePlaceholderPattern = regexp.MustCompile(`\{(\w+)(?::(0?)(\d+)d)?\}`)
+
+// Characters which are not allowed in filenames on at least one of the supported platforms.
+var illegalFilenameChars = regexp.MustCompile(`[\\/:*?"<>|\x00-\x1f]`)
+
+// Values which are available to filename templates.
+type TemplateValues struct {
+	Series  string
+	Season  int
+	Episode int
+	Title   string
+	Year    int
+}
+
+// A template like "{series} - S{season:02d}E{episode:02d} - {title}" used to build output filenames.
+type FilenameTemplate struct {
+	template string
+	// Width the bare {season} and {episode} placeholders are zero padded to, 0 if they are not padded.
+	padding int
+}
+
+// Parses the given template. Unknown placeholders or invalid width specifiers result in an error.
+func ParseFilenameTemplate(template string) (*FilenameTemplate, error) {
+	if strings.TrimSpace(template) == "" {
+		return nil, errors.New("The filename template must not be empty")
+	}
+
+	for _, match := range templatePlaceholderPattern.FindAllStringSubmatch(template, -1) {
+		numeric, known := templatePlaceholders[match[1]]
+		if !known {
+			return nil, errors.New(fmt.Sprintf("Unknown placeholder {%s} in filename template. Known placeholders are {series}, {season}, {episode}, {title} and {year}", match[1]))
+		}
+
+		if match[3] != "" && !numeric {
+			return nil, errors.New(fmt.Sprintf("Placeholder {%s} does not support a width specifier", match[1]))
+		}
+	}
+
+	// Braces which are not part of a valid placeholder are most likely typos
+	remaining := templatePlaceholderPattern.ReplaceAllString(template, "")
+	if strings.ContainsAny(remaining, "{}") {
+		return nil, errors.New(fmt.Sprintf("Malformed placeholder in filename template '%s'", template))
+	}
+
+	return &FilenameTemplate{template: template}, nil
+}
+
+// Returns a copy of the template which pads the numbers of the bare {season} and {episode}
+// placeholders with zeros to the given width, e.g. 2 for S01E01 or 3 for S001E001. Placeholders with
+// a width specifier like {season:02d} keep their width. Numbers which are wider than the padding,
+// like the episode 100 at a width of 2, are never cut off.
+func (tpl *FilenameTemplate) WithPadding(width int) (*FilenameTemplate, error) {
+	if width < 0 || width > MaxTemplatePadding {
+		return nil, errors.New(fmt.Sprintf("The padding must be between 0 and %d, got %d", MaxTemplatePadding, width))
+	}
+
+	padded := *tpl
+	padded.padding = width
+	return &padded, nil
+}
+
+// Builds the filename (without extension) for the given values. All values are sanitized, so
+// they cannot contain characters which are illegal in filenames.
+func (tpl *FilenameTemplate) Format(values TemplateValues) string {
+	result := templatePlaceholderPattern.ReplaceAllStringFunc(tpl.template, func(placeholder string) string {
+		match := templatePlaceholderPattern.FindStringSubmatch(placeholder)
+
+		var number int
+		zeroPadded, width := match[2] == "0", match[3]
+		switch match[1] {
+		case "series":
+			return SanitizeFilename(values.Series)
+		case "title":
+			return SanitizeFilename(values.Title)
+		case "season", "episode":
+			number = values.Season
+			if match[1] == "episode" {
+				number = values.Episode
+			}
+
+			if width == "" && tpl.padding > 0 {
+				zeroPadded, width = true, strconv.Itoa(tpl.padding)
+			}
+		case "year":
+			if values.Year == 0 {
+				return ""
+			}
+
+			number = values.Year
+		}
+
+		return formatNumber(number, zeroPadded, width)
+	})
+
+	return strings.TrimSpace(result)
+}
+
+// Checks if the template contains the placeholder with the given name.
+func (tpl *FilenameTemplate) usesPlaceholder(name string) bool {
+	for _, match := range templatePlaceholderPattern.FindAllStringSubmatch(tpl.template, -1) {
+		if match[1] == name {
+			return true
+		}
+	}
+
+	return false
+}
+
+func formatNumber(number int, zeroPadded bool, width string) string {
+	if width == "" {
+		return strconv.Itoa(number)
+	}
+
+	if zeroPadded {
+		return fmt.Sprintf("%0*d", mustAtoi(width), number)
+	}
+
+	return fmt.Sprintf("%*d", mustAtoi(width), number)
+}
+
+func mustAtoi(value string) int {
+	number, _ := strconv.Atoi(value)
+	return number
+}
+
+// Replaces all characters which are not allowed in filenames on Windows or Unix systems. The name
+// is normalized to the composed Unicode form (NFC), so the same title always results in the same
+// bytes, regardless of how the server stored it. Invalid UTF-8 is replaced. With -ascii, the name
+// is transliterated to ASCII as well.
+func SanitizeFilename(name string) string {
+	name = norm.NFC.String(strings.ToValidUTF8(name, "_"))
+	if asciiFilenames {
+		name = transliterateToAscii(name)
+	}
+
+	name = illegalFilenameChars.ReplaceAllString(name, "_")
+
+	// Windows does not allow filenames ending with a dot or a space
+	return strings.TrimRight(name, ". ")
+}
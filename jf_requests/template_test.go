@@ -0,0 +1,70 @@
+package jf_requests
+
+import "testing"
+
+func TestFilenameTemplateWithPadding(t *testing.T) {
+	tests := []struct {
+		name     string
+		template string
+		width    int
+		values   TemplateValues
+		want     string
+	}{
+		{name: "no padding", template: "S{season}E{episode}", width: 0, values: TemplateValues{Season: 1, Episode: 5}, want: "S1E5"},
+		{name: "width 1", template: "S{season}E{episode}", width: 1, values: TemplateValues{Season: 1, Episode: 5}, want: "S1E5"},
+		{name: "width 2", template: "S{season}E{episode}", width: 2, values: TemplateValues{Season: 1, Episode: 5}, want: "S01E05"},
+		{name: "width 3", template: "S{season}E{episode}", width: 3, values: TemplateValues{Season: 1, Episode: 5}, want: "S001E005"},
+		{name: "zero", template: "S{season}E{episode}", width: 2, values: TemplateValues{Season: 0, Episode: 0}, want: "S00E00"},
+		{name: "wider than width 1", template: "S{season}E{episode}", width: 1, values: TemplateValues{Season: 12, Episode: 34}, want: "S12E34"},
+		{name: "wider than width 2", template: "S{season}E{episode}", width: 2, values: TemplateValues{Season: 1, Episode: 100}, want: "S01E100"},
+		{name: "wider than width 3", template: "S{season}E{episode}", width: 3, values: TemplateValues{Season: 2024, Episode: 1234}, want: "S2024E1234"},
+		{name: "width specifier is kept", template: "S{season:02d}E{episode}", width: 3, values: TemplateValues{Season: 1, Episode: 5}, want: "S01E005"},
+		{name: "year is not padded", template: "{title} ({year})", width: 3, values: TemplateValues{Title: "Pilot", Year: 2001}, want: "Pilot (2001)"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			tpl, err := ParseFilenameTemplate(test.template)
+			if err != nil {
+				t.Fatalf("ParseFilenameTemplate(%q) failed: %s", test.template, err)
+			}
+
+			padded, err := tpl.WithPadding(test.width)
+			if err != nil {
+				t.Fatalf("WithPadding(%d) failed: %s", test.width, err)
+			}
+
+			if got := padded.Format(test.values); got != test.want {
+				t.Errorf("Format() = %q, want %q", got, test.want)
+			}
+		})
+	}
+}
+
+func TestFilenameTemplateWithPaddingKeepsOriginal(t *testing.T) {
+	tpl, err := ParseFilenameTemplate("S{season}E{episode}")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := tpl.WithPadding(2); err != nil {
+		t.Fatal(err)
+	}
+
+	if got := tpl.Format(TemplateValues{Season: 1, Episode: 5}); got != "S1E5" {
+		t.Errorf("Format() of the original template = %q, want %q", got, "S1E5")
+	}
+}
+
+func TestFilenameTemplateWithInvalidPadding(t *testing.T) {
+	tpl, err := ParseFilenameTemplate("S{season}E{episode}")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, width := range []int{-1, MaxTemplatePadding + 1} {
+		if _, err := tpl.WithPadding(width); err == nil {
+			t.Errorf("WithPadding(%d) succeeded, want an error", width)
+		}
+	}
+}
@@ -0,0 +1,130 @@
+package jf_requests
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+)
+
+// Name of the directory inside the users config dir where the JellyfinDownloader stores its files.
+const ConfigDirName = "jellyfindownloader"
+
+// A cached authentication for a single server.
+type CachedAuth struct {
+	BaseUrl  string
+	Username string
+	Token    string
+	UserId   string
+}
+
+// Set by -profile: the tokens of the profile are cached separately, so multiple profiles of the
+// same server can log in as different users.
+var tokenCacheProfile string
+
+// Caches the authentication tokens under the given profile.
+func UseTokenCacheProfile(name string) {
+	tokenCacheProfile = name
+}
+
+// Returns the key of the cache entry for the given server.
+func getTokenCacheKey(baseUrl string) string {
+	if tokenCacheProfile == "" {
+		return baseUrl
+	}
+
+	return fmt.Sprintf("%s#%s", baseUrl, tokenCacheProfile)
+}
+
+// Returns the path of the file the authentication tokens are cached in.
+func GetTokenCachePath() (string, error) {
+	configDir, err := os.UserConfigDir()
+	if err != nil {
+		return "", err
+	}
+
+	return filepath.Join(configDir, ConfigDirName, "tokens.json"), nil
+}
+
+func readTokenCache() map[string]CachedAuth {
+	cache := make(map[string]CachedAuth)
+
+	path, err := GetTokenCachePath()
+	if err != nil {
+		return cache
+	}
+
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return cache
+	}
+
+	if err := json.Unmarshal(content, &cache); err != nil {
+		slog.Debug("Ignoring malformed token cache", "path", path, "error", err)
+		return make(map[string]CachedAuth)
+	}
+
+	return cache
+}
+
+func writeTokenCache(cache map[string]CachedAuth) error {
+	path, err := GetTokenCachePath()
+	if err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return err
+	}
+
+	content, err := json.MarshalIndent(cache, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	return os.WriteFile(path, content, 0600)
+}
+
+// Returns the cached authentication for the given server. If username is not empty, the cached
+// authentication must belong to that user. Returns nil if there is no matching cache entry.
+func LoadCachedAuth(baseUrl string, username string) *AuthResponse {
+	cached, ok := readTokenCache()[getTokenCacheKey(baseUrl)]
+	if !ok || (username != "" && cached.Username != username) {
+		return nil
+	}
+
+	return &AuthResponse{Token: cached.Token, UserId: cached.UserId}
+}
+
+// Stores the given authentication in the token cache.
+func SaveCachedAuth(baseUrl string, username string, auth *AuthResponse) error {
+	cache := readTokenCache()
+	cache[getTokenCacheKey(baseUrl)] = CachedAuth{BaseUrl: baseUrl, Username: username, Token: auth.Token, UserId: auth.UserId}
+
+	if err := writeTokenCache(cache); err != nil {
+		return errors.New(fmt.Sprintf("Failed to write token cache: %s", err))
+	}
+
+	return nil
+}
+
+// Removes the cached authentication for the given server.
+func RemoveCachedAuth(baseUrl string) error {
+	cache := readTokenCache()
+	key := getTokenCacheKey(baseUrl)
+	if _, ok := cache[key]; !ok {
+		return nil
+	}
+
+	delete(cache, key)
+	return writeTokenCache(cache)
+}
+
+// Checks if the given authentication is still accepted by the server.
+func ValidateAuth(ctx context.Context, baseUrl string, auth *AuthResponse) error {
+	_, err := MakeRequest(ctx, auth.Token, BuildUrl(baseUrl, fmt.Sprintf("Users/%s", auth.UserId), nil), "GET", nil)
+	return err
+}
@@ -0,0 +1,87 @@
+package jf_requests
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// Container of transcoded downloads if no other container was requested.
+const TranscodeContainer = "mkv"
+
+// Requested quality of a transcoded download.
+type Quality struct {
+	// Maximum width of the video in pixels; 0 means the original width is kept.
+	MaxWidth int
+	// Maximum video bitrate in bits per second.
+	VideoBitRate int
+	// Bitrate of the transcoded audio stream in bits per second.
+	AudioBitRate int
+}
+
+var qualityPresets = map[string]Quality{
+	"2160p": {MaxWidth: 3840, VideoBitRate: 40_000_000, AudioBitRate: 320_000},
+	"1080p": {MaxWidth: 1920, VideoBitRate: 8_000_000, AudioBitRate: 192_000},
+	"720p":  {MaxWidth: 1280, VideoBitRate: 4_000_000, AudioBitRate: 192_000},
+	"480p":  {MaxWidth: 854, VideoBitRate: 1_500_000, AudioBitRate: 128_000},
+}
+
+var bitratePattern = regexp.MustCompile(`^(\d+(?:\.\d+)?)\s*([kKmM]?)(?:bit|bps|b)?$`)
+
+// Parses a quality like 1080p, 720p or 480p, or an explicit maximum bitrate like 3M or 2500k.
+func ParseQuality(quality string) (*Quality, error) {
+	if preset, ok := qualityPresets[strings.ToLower(quality)]; ok {
+		return &preset, nil
+	}
+
+	match := bitratePattern.FindStringSubmatch(strings.TrimSpace(quality))
+	if match == nil {
+		return nil, errors.New(fmt.Sprintf("Invalid quality '%s'. Use 2160p, 1080p, 720p, 480p or a bitrate like 3M or 2500k", quality))
+	}
+
+	value, _ := strconv.ParseFloat(match[1], 64)
+	switch strings.ToLower(match[2]) {
+	case "k":
+		value *= 1_000
+	case "m":
+		value *= 1_000_000
+	}
+
+	if value < 100_000 {
+		return nil, errors.New(fmt.Sprintf("The bitrate %s is too low, it must be at least 100k", quality))
+	}
+
+	return &Quality{VideoBitRate: int(value), AudioBitRate: 192_000}, nil
+}
+
+// Returns the link to a transcoded stream of the given item in the requested quality and container. If
+// audioStreamIndex is not negative, the transcoded stream only contains the audio stream with this index.
+func GetTranscodeLinkForId(baseUrl string, token string, id string, mediaSourceId string, quality *Quality, container string, audioStreamIndex int) string {
+	codecs := supportedContainers[container]
+
+	params := url.Values{}
+	params.Set("static", "false")
+	params.Set("container", container)
+	params.Set("videoCodec", codecs.FallbackVideo)
+	params.Set("audioCodec", codecs.FallbackAudio)
+	params.Set("videoBitRate", strconv.Itoa(quality.VideoBitRate))
+	params.Set("audioBitRate", strconv.Itoa(quality.AudioBitRate))
+	params.Set("api_key", token)
+
+	if quality.MaxWidth > 0 {
+		params.Set("maxWidth", strconv.Itoa(quality.MaxWidth))
+	}
+
+	if mediaSourceId != "" {
+		params.Set("mediaSourceId", mediaSourceId)
+	}
+
+	if audioStreamIndex >= 0 {
+		params.Set("audioStreamIndex", strconv.Itoa(audioStreamIndex))
+	}
+
+	return BuildUrl(baseUrl, fmt.Sprintf("Videos/%s/stream.%s", id, container), params)
+}
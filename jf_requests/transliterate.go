@@ -0,0 +1,85 @@
+package jf_requests
+
+import (
+	"strings"
+	"unicode"
+
+	"golang.org/x/text/unicode/norm"
+)
+
+// Set by -ascii: filenames are restricted to ASCII characters.
+var asciiFilenames bool
+
+// Restricts all filenames which are built from titles to ASCII characters, for filesystems or
+// players which do not handle other characters well.
+func EnableAsciiFilenames() {
+	asciiFilenames = true
+}
+
+// Transliterations of characters which do not decompose into an ASCII letter and a diacritic.
+// Only lowercase letters are listed; uppercase letters use the capitalized transliteration.
+var asciiTransliterations = map[rune]string{
+	'ß': "ss", 'æ': "ae", 'œ': "oe", 'ø': "o", 'đ': "d", 'ł': "l", 'þ': "th", 'ð': "d", 'ı': "i",
+	'‘': "'", '’': "'", '‚': "'", '“': "\"", '”': "\"", '„': "\"", '«': "\"", '»': "\"",
+	'–': "-", '—': "-", '‐': "-", '…': "...", '·': "-",
+
+	// Cyrillic, following the common romanization of Russian and Ukrainian titles
+	'а': "a", 'б': "b", 'в': "v", 'г': "g", 'ґ': "g", 'д': "d", 'е': "e", 'ё': "yo", 'є': "ye",
+	'ж': "zh", 'з': "z", 'и': "i", 'і': "i", 'ї': "yi", 'й': "y", 'к': "k", 'л': "l", 'м': "m",
+	'н': "n", 'о': "o", 'п': "p", 'р': "r", 'с': "s", 'т': "t", 'у': "u", 'ф': "f", 'х': "kh",
+	'ц': "ts", 'ч': "ch", 'ш': "sh", 'щ': "shch", 'ъ': "", 'ы': "y", 'ь': "", 'э': "e", 'ю': "yu",
+	'я': "ya",
+}
+
+// Returns the ASCII transliteration of the given character and false if there is none.
+func transliterateRune(char rune) (string, bool) {
+	if char < unicode.MaxASCII {
+		return string(char), true
+	}
+
+	lower := unicode.ToLower(char)
+	if replacement, ok := asciiTransliterations[lower]; ok {
+		if lower != char && replacement != "" {
+			replacement = strings.ToUpper(replacement[:1]) + replacement[1:]
+		}
+
+		return replacement, true
+	}
+
+	// Combining marks which did not compose with the preceding letter are dropped like the
+	// marks of decomposed letters
+	if unicode.Is(unicode.Mn, char) {
+		return "", true
+	}
+
+	// Accented letters and compatibility characters like fullwidth letters decompose into ASCII
+	// letters and combining marks, which are dropped
+	var result strings.Builder
+	for _, decomposed := range norm.NFKD.String(string(char)) {
+		if decomposed < unicode.MaxASCII {
+			result.WriteRune(decomposed)
+		} else if !unicode.Is(unicode.Mn, decomposed) {
+			return "", false
+		}
+	}
+
+	return result.String(), result.Len() > 0
+}
+
+// Transliterates the name to ASCII. Characters without an ASCII equivalent, like Japanese
+// characters or emoji, are replaced by an underscore; consecutive ones by a single underscore.
+func transliterateToAscii(name string) string {
+	var result strings.Builder
+	replaced := false
+	for _, char := range name {
+		if replacement, ok := transliterateRune(char); ok {
+			result.WriteString(replacement)
+			replaced = false
+		} else if !replaced {
+			result.WriteRune('_')
+			replaced = true
+		}
+	}
+
+	return result.String()
+}
@@ -0,0 +1,64 @@
+package jf_requests
+
+import "testing"
+
+func TestTransliterateToAscii(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+		want  string
+	}{
+		{name: "ascii", input: "The Office (US)", want: "The Office (US)"},
+		{name: "accents", input: "Amélie à Paris", want: "Amelie a Paris"},
+		{name: "special letters", input: "Straße Æon Łódź", want: "Strasse Aeon Lodz"},
+		{name: "punctuation", input: "Don’t Stop – „Now“…", want: "Don't Stop - \"Now\"..."},
+		{name: "fullwidth letters", input: "ＡＢＣ１２３", want: "ABC123"},
+		{name: "japanese", input: "進撃の巨人", want: "_"},
+		{name: "japanese with latin", input: "Attack on Titan 進撃の巨人 S01", want: "Attack on Titan _ S01"},
+		{name: "cyrillic", input: "Война и мир", want: "Voyna i mir"},
+		{name: "cyrillic digraphs", input: "Щука Ёлка Хорошо", want: "Shchuka Yolka Khorosho"},
+		{name: "cyrillic upper case", input: "ЖУК", want: "ZhUK"},
+		{name: "cyrillic signs", input: "Объявление", want: "Obyavlenie"},
+		{name: "ukrainian", input: "Їжак Ґанок Євген", want: "Yizhak Ganok Yevgen"},
+		{name: "emoji", input: "Movie 🎬🍿 Night", want: "Movie _ Night"},
+		{name: "emoji with variation selector", input: "I ❤️ NY", want: "I _ NY"},
+		{name: "emoji sequence", input: "Family 👨‍👩‍👧", want: "Family _"},
+		{name: "decomposed accent", input: "Ame\u0301lie", want: "Amelie"},
+		{name: "combining mark without precomposed letter", input: "q\u0301x\u0323", want: "qx"},
+		{name: "leading combining mark", input: "\u0301Pilot", want: "Pilot"},
+		{name: "invalid utf-8", input: "Bad\xff\xfeName", want: "Bad_Name"},
+		{name: "truncated utf-8", input: "Name\xe3\x81", want: "Name_"},
+		{name: "empty", input: "", want: ""},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if got := transliterateToAscii(test.input); got != test.want {
+				t.Errorf("transliterateToAscii(%q) = %q, want %q", test.input, got, test.want)
+			}
+		})
+	}
+}
+
+func TestSanitizeFilenameAscii(t *testing.T) {
+	previous := asciiFilenames
+	EnableAsciiFilenames()
+	t.Cleanup(func() { asciiFilenames = previous })
+
+	tests := []struct {
+		input string
+		want  string
+	}{
+		{input: "Amélie: Le Film?", want: "Amelie_ Le Film_"},
+		{input: "Ame\u0301lie", want: "Amelie"},
+		{input: "進撃の巨人", want: "_"},
+		{input: "Bad\xffName", want: "Bad_Name"},
+		{input: "Movie 🎬.", want: "Movie _"},
+	}
+
+	for _, test := range tests {
+		if got := SanitizeFilename(test.input); got != test.want {
+			t.Errorf("SanitizeFilename(%q) = %q, want %q", test.input, got, test.want)
+		}
+	}
+}
@@ -0,0 +1,32 @@
+package jf_requests
+
+import (
+	"net/url"
+	"strings"
+)
+
+// Returns the URL of the given API path on the server, e.g. "Users/<id>/Items", with the given
+// query parameters, which may be nil. Jellyfin can be served under a subpath like
+// https://host/jellyfin, therefore the path is resolved relative to the base URL instead of
+// replacing its path. Trailing slashes of the base URL and leading slashes of the path do not
+// matter. The path is escaped, so it must not be escaped already.
+func BuildUrl(baseUrl string, path string, query url.Values) string {
+	base, err := url.Parse(baseUrl)
+	if err != nil {
+		// Invalid base URLs are rejected by the request, the URL only needs to be recognizable in the error
+		return strings.TrimRight(baseUrl, "/") + "/" + strings.TrimLeft(path, "/")
+	}
+
+	// Without a trailing slash, the last segment of the subpath would be replaced
+	base.Path = strings.TrimRight(base.Path, "/") + "/"
+	base.RawPath = ""
+	base.RawQuery = ""
+	base.Fragment = ""
+
+	reference := &url.URL{Path: strings.TrimLeft(path, "/")}
+	if len(query) > 0 {
+		reference.RawQuery = query.Encode()
+	}
+
+	return base.ResolveReference(reference).String()
+}
@@ -0,0 +1,49 @@
+package jf_requests
+
+import (
+	"net/url"
+	"testing"
+)
+
+func TestBuildUrl(t *testing.T) {
+	tests := []struct {
+		name    string
+		baseUrl string
+		path    string
+		query   url.Values
+		want    string
+	}{
+		{name: "no subpath", baseUrl: "http://jellyfin.local:8096", path: "Users/Me", want: "http://jellyfin.local:8096/Users/Me"},
+		{name: "no subpath with trailing slash", baseUrl: "http://jellyfin.local:8096/", path: "Users/Me", want: "http://jellyfin.local:8096/Users/Me"},
+		{name: "subpath", baseUrl: "https://example.com/jellyfin", path: "Users/Me", want: "https://example.com/jellyfin/Users/Me"},
+		{name: "subpath with trailing slash", baseUrl: "https://example.com/jellyfin/", path: "Users/Me", want: "https://example.com/jellyfin/Users/Me"},
+		{name: "nested subpath", baseUrl: "https://example.com/media/jellyfin", path: "System/Info", want: "https://example.com/media/jellyfin/System/Info"},
+		{name: "leading slash of the path", baseUrl: "https://example.com/jellyfin", path: "/Users/Me", want: "https://example.com/jellyfin/Users/Me"},
+		{name: "empty query", baseUrl: "https://example.com/jellyfin", path: "Items", query: url.Values{}, want: "https://example.com/jellyfin/Items"},
+		{
+			name:    "query",
+			baseUrl: "https://example.com/jellyfin/",
+			path:    "Users/1/Items",
+			query:   url.Values{"ParentId": {"abc"}, "Recursive": {"true"}},
+			want:    "https://example.com/jellyfin/Users/1/Items?ParentId=abc&Recursive=true",
+		},
+		{
+			name:    "query is escaped",
+			baseUrl: "http://jellyfin.local",
+			path:    "Items",
+			query:   url.Values{"searchTerm": {"Tom & Jerry"}},
+			want:    "http://jellyfin.local/Items?searchTerm=Tom+%26+Jerry",
+		},
+		{name: "query of the base URL is dropped", baseUrl: "http://jellyfin.local/jf?x=1#top", path: "Items", want: "http://jellyfin.local/jf/Items"},
+		{name: "path is escaped", baseUrl: "http://jellyfin.local", path: "Items/a b", want: "http://jellyfin.local/Items/a%20b"},
+		{name: "path with a colon", baseUrl: "http://jellyfin.local", path: "Items/a:b", want: "http://jellyfin.local/Items/a:b"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if got := BuildUrl(test.baseUrl, test.path, test.query); got != test.want {
+				t.Errorf("BuildUrl(%q, %q, %v) = %q, want %q", test.baseUrl, test.path, test.query, got, test.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,233 @@
+package jf_requests
+
+import (
+	"bytes"
+	"context"
+	"crypto/md5"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"hash"
+	"io"
+	"log/slog"
+	"net/http"
+	"os"
+	"regexp"
+	"strings"
+
+	"github.com/fatih/color"
+)
+
+// Suffix of files which failed the verification.
+const CorruptFileSuffix = ".corrupt"
+
+// Error returned when a downloaded file does not match the file on the server.
+type VerificationError struct {
+	Message string
+}
+
+func (err *VerificationError) Error() string {
+	return fmt.Sprintf("Verification failed: %s", err.Message)
+}
+
+// A checksum announced by the server for a response.
+type expectedChecksum struct {
+	Algorithm string
+	Sum       []byte
+}
+
+var hexMd5Pattern = regexp.MustCompile(`^"?([0-9a-fA-F]{32})"?$`)
+
+// Returns the checksum of the response body as announced by the server in the Digest, Repr-Digest,
+// Content-MD5 or ETag header. Returns nil if the server did not announce one.
+func getExpectedChecksum(header http.Header) *expectedChecksum {
+	for _, name := range []string{"Repr-Digest", "Digest"} {
+		for _, entry := range strings.Split(header.Get(name), ",") {
+			algorithm, value, found := strings.Cut(strings.TrimSpace(entry), "=")
+			if !found {
+				continue
+			}
+
+			algorithm = strings.ToLower(algorithm)
+			if algorithm != "sha-256" && algorithm != "md5" {
+				continue
+			}
+
+			if sum, err := base64.StdEncoding.DecodeString(strings.Trim(value, ":")); err == nil {
+				return &expectedChecksum{Algorithm: algorithm, Sum: sum}
+			}
+		}
+	}
+
+	if value := header.Get("Content-MD5"); value != "" {
+		if sum, err := base64.StdEncoding.DecodeString(value); err == nil {
+			return &expectedChecksum{Algorithm: "md5", Sum: sum}
+		}
+	}
+
+	// Some servers use the MD5 of the content as ETag
+	if match := hexMd5Pattern.FindStringSubmatch(header.Get("ETag")); match != nil {
+		sum, _ := hex.DecodeString(match[1])
+		return &expectedChecksum{Algorithm: "md5", Sum: sum}
+	}
+
+	return nil
+}
+
+func computeChecksum(path string, algorithm string) ([]byte, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+
+	defer f.Close()
+
+	var hasher hash.Hash = sha256.New()
+	if algorithm == "md5" {
+		hasher = md5.New()
+	}
+
+	if _, err := io.Copy(hasher, f); err != nil {
+		return nil, err
+	}
+
+	return hasher.Sum(nil), nil
+}
+
+// Verifies the downloaded file at path. The size must match expectedSize, if it is known (> 0).
+// If verifyChecksum is set and the server announced a checksum in the response header, the
+// checksum of the file is compared as well.
+func VerifyDownload(path string, expectedSize int64, header http.Header, verifyChecksum bool) error {
+	info, err := os.Stat(path)
+	if err != nil {
+		return err
+	}
+
+	if expectedSize > 0 && info.Size() != expectedSize {
+		return &VerificationError{Message: fmt.Sprintf("expected %d bytes but got %d bytes", expectedSize, info.Size())}
+	}
+
+	if !verifyChecksum {
+		return nil
+	}
+
+	expected := getExpectedChecksum(header)
+	if expected == nil {
+		slog.Debug("Server did not provide a checksum, only the size was verified", "path", path)
+		return nil
+	}
+
+	actual, err := computeChecksum(path, expected.Algorithm)
+	if err != nil {
+		return errors.New(fmt.Sprintf("Failed to compute checksum of %s: %s", path, err))
+	}
+
+	if !bytes.Equal(actual, expected.Sum) {
+		return &VerificationError{Message: fmt.Sprintf("%s checksum mismatch: expected %x but got %x", expected.Algorithm, expected.Sum, actual)}
+	}
+
+	return nil
+}
+
+// Moves a file which failed the verification out of the way, so it is not mistaken for a complete download.
+func MarkCorrupt(path string) string {
+	corruptPath := path + CorruptFileSuffix
+	if err := os.Rename(path, corruptPath); err != nil {
+		slog.Warn(fmt.Sprintf("Failed to rename corrupt file %s, removing it", path), "error", err)
+		os.Remove(path)
+		return ""
+	}
+
+	return corruptPath
+}
+
+// Fetches the headers the server sends for the file behind the given link, without downloading it.
+// The request counts towards -api-rate and is retried on transient errors.
+func fetchFileHeader(ctx context.Context, link string) (http.Header, error) {
+	return WithRetry(ctx, fmt.Sprintf("HEAD request against %s", RedactUrl(link)), func() (http.Header, error) {
+		req, err := http.NewRequestWithContext(ctx, "HEAD", link, nil)
+		if err != nil {
+			return nil, err
+		}
+
+		if err := waitForApiLimit(ctx); err != nil {
+			return nil, err
+		}
+
+		resp, err := httpClient.Do(req)
+		if err != nil {
+			return nil, &ConnectionError{Err: redactError(err)}
+		}
+
+		resp.Body.Close()
+		if resp.StatusCode != http.StatusOK {
+			return nil, newStatusError(resp, "")
+		}
+
+		return resp.Header, nil
+	})
+}
+
+// Compares an existing file of the same size as on the server with the checksum the server
+// announces for it. If the server announces none, the ETag is compared with the one recorded in
+// the manifest when the file was downloaded. Checksums of local files are cached in the manifest.
+// Returns the reason the file is skipped, or the zero value if it differs and is downloaded again.
+func verifyExistingFile(ctx context.Context, job *DownloadJob, manifest *Manifest) existingFileSkip {
+	sizeOnly := skipSameSize
+
+	header, err := fetchFileHeader(ctx, job.Url)
+	if err != nil {
+		slog.Debug(fmt.Sprintf("Failed to obtain the checksum of %s, only the size was compared", job.Name), "error", err)
+		return sizeOnly
+	}
+
+	var cached FileHash
+	var hasCache bool
+	if manifest != nil {
+		cached, hasCache = manifest.GetHash(job.Outfile)
+	}
+
+	mismatch := func(reason string) existingFileSkip {
+		printMessage(func() {
+			color.Yellow("%s differs from the file on the server (%s), downloading it again", job.Outfile, reason)
+		})
+
+		return existingFileSkip{}
+	}
+
+	if expected := getExpectedChecksum(header); expected != nil {
+		actual, _ := hex.DecodeString(cached.Checksum)
+		if !hasCache || cached.Algorithm != expected.Algorithm {
+			if actual, err = computeChecksum(job.Outfile, expected.Algorithm); err != nil {
+				slog.Warn(fmt.Sprintf("Failed to compute checksum of %s: %s", job.Outfile, err))
+				return sizeOnly
+			}
+
+			if manifest != nil {
+				hash := FileHash{Algorithm: expected.Algorithm, Checksum: hex.EncodeToString(actual), ETag: cached.ETag}
+				if err := manifest.SetHash(job.Outfile, hash); err != nil {
+					slog.Debug(fmt.Sprintf("Failed to store the checksum of %s", job.Outfile), "error", err)
+				}
+			}
+		}
+
+		if !bytes.Equal(actual, expected.Sum) {
+			return mismatch(fmt.Sprintf("%s checksum mismatch", expected.Algorithm))
+		}
+
+		return skipChecksumMatch
+	}
+
+	if etag := header.Get("ETag"); etag != "" && hasCache && cached.ETag != "" {
+		if etag != cached.ETag {
+			return mismatch("the ETag changed since it was downloaded")
+		}
+
+		return skipUnchangedETag
+	}
+
+	slog.Debug(fmt.Sprintf("Server provides no checksum for %s, only the size was compared", job.Name))
+	return sizeOnly
+}